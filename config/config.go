@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"bloat/model"
@@ -19,8 +20,17 @@ type config struct {
 	StaticDirectory string
 	TemplatesPath   string
 	CustomCSS       string
+	DatabasePath    string
 	PostFormats     []model.PostFormat
 	LogFile         string
+	ImageMaxSize    int
+
+	MediaProxy          bool
+	MediaProxyKey       string
+	MediaProxyHosts     []string
+	MediaProxyMaxSize   int64
+	MediaProxyCachePath string
+	MediaProxyCacheSize int64
 }
 
 func (c *config) IsValid() bool {
@@ -29,14 +39,18 @@ func (c *config) IsValid() bool {
 		len(c.ClientScope) < 1 ||
 		len(c.ClientWebsite) < 1 ||
 		len(c.StaticDirectory) < 1 ||
-		len(c.TemplatesPath) < 1 {
+		len(c.TemplatesPath) < 1 ||
+		len(c.DatabasePath) < 1 {
 		return false
 	}
 	return true
 }
 
 func Parse(r io.Reader) (c *config, err error) {
-	c = new(config)
+	c = &config{
+		MediaProxyMaxSize:   40 << 20,
+		MediaProxyCacheSize: 1 << 30,
+	}
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -76,7 +90,7 @@ func Parse(r io.Reader) (c *config, err error) {
 		case "custom_css":
 			c.CustomCSS = val
 		case "database_path":
-			// ignore
+			c.DatabasePath = val
 		case "post_formats":
 			vals := strings.Split(val, ",")
 			var formats []model.PostFormat
@@ -98,6 +112,36 @@ func Parse(r io.Reader) (c *config, err error) {
 			c.PostFormats = formats
 		case "log_file":
 			c.LogFile = val
+		case "image_max_size":
+			size, err := strconv.Atoi(val)
+			if err != nil || size < 0 {
+				return nil, errors.New("invalid config key " + key)
+			}
+			c.ImageMaxSize = size
+		case "media_proxy":
+			c.MediaProxy = val == "true"
+		case "media_proxy_key":
+			c.MediaProxyKey = val
+		case "media_proxy_hosts":
+			c.MediaProxyHosts = nil
+			for _, h := range strings.Split(val, ",") {
+				h = strings.ToLower(strings.TrimSpace(h))
+				if len(h) > 0 {
+					c.MediaProxyHosts = append(c.MediaProxyHosts, h)
+				}
+			}
+		case "media_proxy_max_size", "media_proxy_cache_size":
+			size, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || size < 1 {
+				return nil, errors.New("invalid config key " + key)
+			}
+			if key == "media_proxy_max_size" {
+				c.MediaProxyMaxSize = size << 20
+			} else {
+				c.MediaProxyCacheSize = size << 20
+			}
+		case "media_proxy_cache_path":
+			c.MediaProxyCachePath = val
 		default:
 			return nil, errors.New("invalid config key " + key)
 		}
@@ -0,0 +1,83 @@
+package kv
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	errInvalidKey = errors.New("invalid key")
+	ErrNoSuchKey  = errors.New("no such key")
+)
+
+// Database is a simple key value store which keeps every value in a separate
+// file inside the base directory.
+type Database struct {
+	basedir string
+	m       sync.RWMutex
+}
+
+func NewDatabase(basedir string) (db *Database, err error) {
+	err = os.MkdirAll(basedir, 0755)
+	if err != nil {
+		return
+	}
+	return &Database{
+		basedir: basedir,
+	}, nil
+}
+
+func validKey(key string) bool {
+	return len(key) > 0 && key != "." && key != ".." &&
+		!strings.ContainsAny(key, `/\`)
+}
+
+func (db *Database) Set(key string, val []byte) (err error) {
+	if !validKey(key) {
+		return errInvalidKey
+	}
+	db.m.Lock()
+	defer db.m.Unlock()
+
+	// Write to a temporary file first so that a crash never leaves a
+	// partially written value behind.
+	path := filepath.Join(db.basedir, key)
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, val, 0600)
+	if err != nil {
+		return
+	}
+	return os.Rename(tmp, path)
+}
+
+func (db *Database) Get(key string) (val []byte, err error) {
+	if !validKey(key) {
+		return nil, errInvalidKey
+	}
+	db.m.RLock()
+	defer db.m.RUnlock()
+
+	val, err = ioutil.ReadFile(filepath.Join(db.basedir, key))
+	if os.IsNotExist(err) {
+		return nil, ErrNoSuchKey
+	}
+	return
+}
+
+func (db *Database) Remove(key string) (err error) {
+	if !validKey(key) {
+		return errInvalidKey
+	}
+	db.m.Lock()
+	defer db.m.Unlock()
+
+	err = os.Remove(filepath.Join(db.basedir, key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return
+}
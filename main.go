@@ -11,7 +11,10 @@ import (
 	"strings"
 
 	"bloat/config"
+	"bloat/kv"
+	"bloat/mediaproxy"
 	"bloat/renderer"
+	"bloat/repo"
 	"bloat/service"
 )
 
@@ -40,8 +43,24 @@ func main() {
 		errExit(errors.New("invalid config"))
 	}
 
+	// The proxy is also used for the images of link cards, for users who
+	// don't want to load them from third party sites.
+	var cache *mediaproxy.Cache
+	if config.MediaProxy && len(config.MediaProxyCachePath) > 0 {
+		cache, err = mediaproxy.NewCache(config.MediaProxyCachePath,
+			config.MediaProxyCacheSize)
+		if err != nil {
+			errExit(err)
+		}
+	}
+	mediaProxy, err := mediaproxy.New(config.MediaProxyKey, config.MediaProxy,
+		config.MediaProxyHosts, config.MediaProxyMaxSize, cache)
+	if err != nil {
+		errExit(err)
+	}
+
 	templatesGlobPattern := filepath.Join(config.TemplatesPath, "*")
-	renderer, err := renderer.NewRenderer(templatesGlobPattern)
+	renderer, err := renderer.NewRenderer(templatesGlobPattern, mediaProxy)
 	if err != nil {
 		errExit(err)
 	}
@@ -65,9 +84,17 @@ func main() {
 		logger = log.New(lf, "", log.LstdFlags)
 	}
 
+	db, err := kv.NewDatabase(config.DatabasePath)
+	if err != nil {
+		errExit(err)
+	}
+	draftRepo := repo.NewDraftRepo(db)
+	muteRuleRepo := repo.NewMuteRuleRepo(db)
+
 	s := service.NewService(config.ClientName, config.ClientScope,
 		config.ClientWebsite, customCSS, config.SingleInstance,
-		config.PostFormats, renderer)
+		config.PostFormats, config.ImageMaxSize, renderer, draftRepo,
+		muteRuleRepo, mediaProxy)
 	handler := service.NewHandler(s, logger, config.StaticDirectory)
 
 	logger.Println("listening on", config.ListenAddress)
@@ -35,6 +35,7 @@ type Account struct {
 	Fields         []Field         `json:"fields"`
 	Bot            bool            `json:"bot"`
 	Pleroma        *AccountPleroma `json:"pleroma"`
+	Source         *AccountSource  `json:"source"`
 }
 
 // Field is a Mastodon account profile field.
@@ -141,11 +142,15 @@ func (c *Client) AccountUpdate(ctx context.Context, profile *Profile) (*Account,
 	return &account, nil
 }
 
-// GetAccountStatuses return statuses by specified accuont.
-func (c *Client) GetAccountStatuses(ctx context.Context, id string, onlyMedia bool, pg *Pagination) ([]*Status, error) {
+// GetAccountStatuses return statuses by specified accuont. Only statuses
+// with the tag are returned if tagged isn't empty.
+func (c *Client) GetAccountStatuses(ctx context.Context, id string, onlyMedia bool, tagged string, pg *Pagination) ([]*Status, error) {
 	var statuses []*Status
 	params := url.Values{}
 	params.Set("only_media", strconv.FormatBool(onlyMedia))
+	if len(tagged) > 0 {
+		params.Set("tagged", tagged)
+	}
 	err := c.doAPI(ctx, http.MethodGet, fmt.Sprintf("/api/v1/accounts/%s/statuses", url.PathEscape(string(id))), params, &statuses, pg)
 	if err != nil {
 		return nil, err
@@ -153,6 +158,18 @@ func (c *Client) GetAccountStatuses(ctx context.Context, id string, onlyMedia bo
 	return statuses, nil
 }
 
+// GetAccountPinnedStatuses return statuses pinned by specified account.
+func (c *Client) GetAccountPinnedStatuses(ctx context.Context, id string) ([]*Status, error) {
+	var statuses []*Status
+	params := url.Values{}
+	params.Set("pinned", "true")
+	err := c.doAPI(ctx, http.MethodGet, fmt.Sprintf("/api/v1/accounts/%s/statuses", url.PathEscape(string(id))), params, &statuses, nil)
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
 // GetAccountFollowers return followers list.
 func (c *Client) GetAccountFollowers(ctx context.Context, id string, pg *Pagination) ([]*Account, error) {
 	var accounts []*Account
@@ -183,6 +200,27 @@ func (c *Client) GetBlocks(ctx context.Context, pg *Pagination) ([]*Account, err
 	return accounts, nil
 }
 
+// GetEndorsements return accounts endorsed by the current user.
+func (c *Client) GetEndorsements(ctx context.Context, pg *Pagination) ([]*Account, error) {
+	var accounts []*Account
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/endorsements", nil, &accounts, pg)
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetAccountEndorsements return accounts endorsed by specified account.
+// Only newer servers show the endorsements of other accounts.
+func (c *Client) GetAccountEndorsements(ctx context.Context, id string, pg *Pagination) ([]*Account, error) {
+	var accounts []*Account
+	err := c.doAPI(ctx, http.MethodGet, fmt.Sprintf("/api/v1/accounts/%s/endorsements", url.PathEscape(string(id))), nil, &accounts, pg)
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
 // Relationship hold information for relation-ship to the account.
 type Relationship struct {
 	ID                  string `json:"id"`
@@ -197,6 +235,7 @@ type Relationship struct {
 	DomainBlocking      bool   `json:"domain_blocking"`
 	ShowingReblogs      bool   `json:"showing_reblogs"`
 	Endorsed            bool   `json:"endorsed"`
+	Note                string `json:"note"`
 }
 
 // AccountFollow follow the account.
@@ -267,6 +306,41 @@ func (c *Client) AccountUnmute(ctx context.Context, id string) (*Relationship, e
 	return &relationship, nil
 }
 
+// AccountEndorse features the account on the profile of the current user.
+func (c *Client) AccountEndorse(ctx context.Context, id string) (*Relationship, error) {
+	var relationship Relationship
+	err := c.doAPI(ctx, http.MethodPost, fmt.Sprintf("/api/v1/accounts/%s/pin", url.PathEscape(string(id))), nil, &relationship, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &relationship, nil
+}
+
+// AccountUnendorse removes the account from the profile of the current
+// user.
+func (c *Client) AccountUnendorse(ctx context.Context, id string) (*Relationship, error) {
+	var relationship Relationship
+	err := c.doAPI(ctx, http.MethodPost, fmt.Sprintf("/api/v1/accounts/%s/unpin", url.PathEscape(string(id))), nil, &relationship, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &relationship, nil
+}
+
+// SetAccountNote sets the personal note on the account, which is only
+// visible to the current user. An empty comment removes the note.
+func (c *Client) SetAccountNote(ctx context.Context, id string, comment string) (*Relationship, error) {
+	params := url.Values{}
+	params.Set("comment", comment)
+
+	var relationship Relationship
+	err := c.doAPI(ctx, http.MethodPost, fmt.Sprintf("/api/v1/accounts/%s/note", url.PathEscape(string(id))), params, &relationship, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &relationship, nil
+}
+
 // GetAccountRelationships return relationship for the account.
 func (c *Client) GetAccountRelationships(ctx context.Context, ids []string) ([]*Relationship, error) {
 	params := url.Values{}
@@ -0,0 +1,18 @@
+package mastodon
+
+import (
+	"time"
+)
+
+// Announcement hold information for an announcement of the instance.
+type Announcement struct {
+	ID          string     `json:"id"`
+	Content     string     `json:"content"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	AllDay      bool       `json:"all_day"`
+	PublishedAt time.Time  `json:"published_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Read        bool       `json:"read"`
+	Emojis      []Emoji    `json:"emojis"`
+}
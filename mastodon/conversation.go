@@ -0,0 +1,37 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Conversation hold information for a direct message conversation.
+type Conversation struct {
+	ID         string     `json:"id"`
+	Accounts   []*Account `json:"accounts"`
+	LastStatus *Status    `json:"last_status"`
+	Unread     bool       `json:"unread"`
+}
+
+// GetConversations return direct message conversations.
+func (c *Client) GetConversations(ctx context.Context, pg *Pagination) ([]*Conversation, error) {
+	var conversations []*Conversation
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/conversations", nil, &conversations, pg)
+	if err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// MarkConversationAsRead marks a conversation as read.
+func (c *Client) MarkConversationAsRead(ctx context.Context, id string) error {
+	return c.doAPI(ctx, http.MethodPost, fmt.Sprintf("/api/v1/conversations/%s/read", url.PathEscape(id)), nil, nil, nil)
+}
+
+// DeleteConversation removes a conversation. The statuses of the
+// conversation are kept.
+func (c *Client) DeleteConversation(ctx context.Context, id string) error {
+	return c.doAPI(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/conversations/%s", url.PathEscape(id)), nil, nil, nil)
+}
@@ -0,0 +1,22 @@
+package mastodon
+
+import "strconv"
+
+// Count is a number which servers encode either as a JSON number or as a
+// string.
+type Count int64
+
+func (n *Count) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 1 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	v, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return err
+	}
+	*n = Count(v)
+	return nil
+}
@@ -0,0 +1,32 @@
+package mastodon
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GetDomainBlocks return the domains blocked by the current user.
+func (c *Client) GetDomainBlocks(ctx context.Context, pg *Pagination) ([]string, error) {
+	var domains []string
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/domain_blocks", nil, &domains, pg)
+	if err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// BlockDomain hides everything from the domain. Followers from the domain
+// are removed.
+func (c *Client) BlockDomain(ctx context.Context, domain string) error {
+	params := url.Values{}
+	params.Set("domain", domain)
+	return c.doAPI(ctx, http.MethodPost, "/api/v1/domain_blocks", params, nil, nil)
+}
+
+// UnblockDomain removes the block of the domain.
+func (c *Client) UnblockDomain(ctx context.Context, domain string) error {
+	params := url.Values{}
+	params.Set("domain", domain)
+	return c.doAPI(ctx, http.MethodDelete, "/api/v1/domain_blocks", params, nil, nil)
+}
@@ -2,6 +2,7 @@ package mastodon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -48,3 +49,197 @@ func (c *Client) AddFilter(ctx context.Context, phrase string, context []string,
 func (c *Client) RemoveFilter(ctx context.Context, id string) error {
 	return c.doAPI(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/filters/%s", id), nil, nil, nil)
 }
+
+// FilterV2 is a filter of the v2 API, which matches any of its keywords.
+type FilterV2 struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	Context      []string        `json:"context"`
+	ExpiresAt    *time.Time      `json:"expires_at"`
+	FilterAction string          `json:"filter_action"`
+	Keywords     []FilterKeyword `json:"keywords"`
+}
+
+// FilterKeyword is a keyword of a filter.
+type FilterKeyword struct {
+	ID        string `json:"id"`
+	Keyword   string `json:"keyword"`
+	WholeWord bool   `json:"whole_word"`
+
+	// Destroy removes the keyword when the filter is updated.
+	Destroy bool `json:"-"`
+}
+
+// FilterResult is a filter which matched a status.
+type FilterResult struct {
+	Filter         FilterV2 `json:"filter"`
+	KeywordMatches []string `json:"keyword_matches"`
+}
+
+// FilterParams are the settings of a filter to create or update.
+type FilterParams struct {
+	Title        string
+	Context      []string
+	FilterAction string
+
+	// ExpiresIn is the time until the filter expires. A nil value keeps
+	// the current expiry and zero makes the filter permanent.
+	ExpiresIn *time.Duration
+
+	Keywords []FilterKeyword
+}
+
+func (p *FilterParams) values() url.Values {
+	params := url.Values{}
+	params.Set("title", p.Title)
+	for i := range p.Context {
+		params.Add("context[]", p.Context[i])
+	}
+	params.Set("filter_action", p.FilterAction)
+	if p.ExpiresIn != nil {
+		params.Set("expires_in", expiresIn(*p.ExpiresIn))
+	}
+	for i, k := range p.Keywords {
+		key := fmt.Sprintf("keywords_attributes[%d]", i)
+		if len(k.ID) > 0 {
+			params.Set(key+"[id]", k.ID)
+		}
+		if k.Destroy {
+			params.Set(key+"[_destroy]", "true")
+			continue
+		}
+		params.Set(key+"[keyword]", k.Keyword)
+		params.Set(key+"[whole_word]", strconv.FormatBool(k.WholeWord))
+	}
+	return params
+}
+
+func expiresIn(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+func isNotFound(err error) bool {
+	e, ok := err.(Error)
+	return ok && e.IsNotFound()
+}
+
+// errFilterActionUnsupported is returned for filters with the warn action
+// on servers without the v2 API, which don't mark the statuses which match
+// the filters.
+var errFilterActionUnsupported = errors.New("server can only hide filtered statuses")
+
+// GetFiltersV2 returns the filters of the current user. Filters of servers
+// without the v2 API are returned as filters with a single keyword, and v1
+// is true for these servers, as they can only hide filtered statuses.
+func (c *Client) GetFiltersV2(ctx context.Context) (filters []*FilterV2, v1 bool, err error) {
+	err = c.doAPI(ctx, http.MethodGet, "/api/v2/filters", nil, &filters, nil)
+	if isNotFound(err) {
+		v1Filters, err := c.GetFilters(ctx)
+		if err != nil {
+			return nil, true, err
+		}
+		filters = make([]*FilterV2, len(v1Filters))
+		for i, f := range v1Filters {
+			filters[i] = filterFromV1(f)
+		}
+		return filters, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return filters, false, nil
+}
+
+func filterFromV1(f *Filter) *FilterV2 {
+	action := "warn"
+	if f.Irreversible {
+		action = "hide"
+	}
+	return &FilterV2{
+		ID:           f.ID,
+		Title:        f.Phrase,
+		Context:      f.Context,
+		ExpiresAt:    f.ExpiresAt,
+		FilterAction: action,
+		Keywords: []FilterKeyword{{
+			ID:        f.ID,
+			Keyword:   f.Phrase,
+			WholeWord: f.WholeWord,
+		}},
+	}
+}
+
+// CreateFilterV2 creates a filter. Servers without the v2 API get a filter
+// for every keyword.
+func (c *Client) CreateFilterV2(ctx context.Context, p *FilterParams) error {
+	err := c.doAPI(ctx, http.MethodPost, "/api/v2/filters", p.values(), nil, nil)
+	if isNotFound(err) {
+		if p.FilterAction != "hide" {
+			return errFilterActionUnsupported
+		}
+		for _, k := range p.Keywords {
+			if k.Destroy {
+				continue
+			}
+			err = c.doAPI(ctx, http.MethodPost, "/api/v1/filters", p.v1Values(k), nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+// UpdateFilterV2 updates a filter. Servers without the v2 API only know
+// filters with a single keyword, so other keywords are added as new filters.
+func (c *Client) UpdateFilterV2(ctx context.Context, id string, p *FilterParams) error {
+	err := c.doAPI(ctx, http.MethodPut, fmt.Sprintf("/api/v2/filters/%s", url.PathEscape(id)), p.values(), nil, nil)
+	if isNotFound(err) {
+		if p.FilterAction != "hide" {
+			return errFilterActionUnsupported
+		}
+		for _, k := range p.Keywords {
+			switch {
+			case k.Destroy && len(k.ID) > 0:
+				err = c.RemoveFilter(ctx, k.ID)
+			case k.Destroy:
+			case len(k.ID) > 0:
+				err = c.doAPI(ctx, http.MethodPut, fmt.Sprintf("/api/v1/filters/%s", url.PathEscape(k.ID)), p.v1Values(k), nil, nil)
+			default:
+				err = c.doAPI(ctx, http.MethodPost, "/api/v1/filters", p.v1Values(k), nil, nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+func (p *FilterParams) v1Values(k FilterKeyword) url.Values {
+	params := url.Values{}
+	params.Set("phrase", k.Keyword)
+	for i := range p.Context {
+		params.Add("context[]", p.Context[i])
+	}
+	params.Set("irreversible", strconv.FormatBool(p.FilterAction == "hide"))
+	params.Set("whole_word", strconv.FormatBool(k.WholeWord))
+	if p.ExpiresIn != nil {
+		params.Set("expires_in", expiresIn(*p.ExpiresIn))
+	}
+	return params
+}
+
+// DeleteFilterV2 deletes a filter along with its keywords.
+func (c *Client) DeleteFilterV2(ctx context.Context, id string) error {
+	err := c.doAPI(ctx, http.MethodDelete, fmt.Sprintf("/api/v2/filters/%s", url.PathEscape(id)), nil, nil, nil)
+	if isNotFound(err) {
+		return c.RemoveFilter(ctx, id)
+	}
+	return err
+}
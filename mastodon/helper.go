@@ -25,6 +25,10 @@ func (e Error) IsAuthError() bool {
 	return false
 }
 
+func (e Error) IsNotFound() bool {
+	return e.code == http.StatusNotFound
+}
+
 // Base64EncodeFileName returns the base64 data URI format string of the file with the file name.
 func Base64EncodeFileName(filename string) (string, error) {
 	file, err := os.Open(filename)
@@ -3,6 +3,7 @@ package mastodon
 import (
 	"context"
 	"net/http"
+	"strings"
 )
 
 // Instance hold information for mastodon instance.
@@ -19,6 +20,11 @@ type Instance struct {
 	ContactAccount *Account          `json:"account"`
 }
 
+// IsPleroma reports whether the instance runs Pleroma.
+func (i *Instance) IsPleroma() bool {
+	return strings.Contains(i.Version, "Pleroma")
+}
+
 // InstanceStats hold information for mastodon instance stats.
 type InstanceStats struct {
 	UserCount   int64 `json:"user_count"`
@@ -36,6 +42,22 @@ func (c *Client) GetInstance(ctx context.Context) (*Instance, error) {
 	return &instance, nil
 }
 
+// Rule hold information for a rule of the instance.
+type Rule struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// GetInstanceRules return the rules of the instance.
+func (c *Client) GetInstanceRules(ctx context.Context) ([]*Rule, error) {
+	var rules []*Rule
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/instance/rules", nil, &rules, nil)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
 // WeeklyActivity hold information for mastodon weekly activity.
 type WeeklyActivity struct {
 	Week          Unixtime `json:"week"`
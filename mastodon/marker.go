@@ -0,0 +1,37 @@
+package mastodon
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Marker hold information for the read position in a timeline.
+type Marker struct {
+	LastReadID string    `json:"last_read_id"`
+	Version    int64     `json:"version"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// GetMarkers returns the markers of the given timelines, which can be "home"
+// and "notifications", by timeline. Timelines without a marker are missing.
+func (c *Client) GetMarkers(ctx context.Context, timelines ...string) (map[string]*Marker, error) {
+	params := url.Values{}
+	for _, t := range timelines {
+		params.Add("timeline[]", t)
+	}
+	var markers map[string]*Marker
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/markers", params, &markers, nil)
+	if err != nil {
+		return nil, err
+	}
+	return markers, nil
+}
+
+// SetMarker moves the marker of timeline to lastReadID.
+func (c *Client) SetMarker(ctx context.Context, timeline string, lastReadID string) error {
+	params := url.Values{}
+	params.Set(timeline+"[last_read_id]", lastReadID)
+	return c.doAPI(ctx, http.MethodPost, "/api/v1/markers", params, nil, nil)
+}
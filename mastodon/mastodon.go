@@ -34,6 +34,11 @@ type Client struct {
 }
 
 func (c *Client) doAPI(ctx context.Context, method string, uri string, params interface{}, res interface{}, pg *Pagination) error {
+	return c.doAPIWithHeader(ctx, method, uri, nil, params, res, pg)
+}
+
+// doAPIWithHeader is like doAPI, but also sends the given request headers.
+func (c *Client) doAPIWithHeader(ctx context.Context, method string, uri string, header http.Header, params interface{}, res interface{}, pg *Pagination) error {
 	u, err := url.Parse(c.config.Server)
 	if err != nil {
 		return err
@@ -113,6 +118,31 @@ func (c *Client) doAPI(ctx context.Context, method string, uri string, params in
 			return err
 		}
 		ct = mw.FormDataContentType()
+	} else if file, ok := params.(namedReader); ok {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fname := filepath.Base(file.name)
+		err = mw.WriteField("description", fname)
+		if err != nil {
+			return err
+		}
+		part, err := mw.CreateFormFile("file", fname)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, file.r)
+		if err != nil {
+			return err
+		}
+		err = mw.Close()
+		if err != nil {
+			return err
+		}
+		req, err = http.NewRequest(method, u.String(), &buf)
+		if err != nil {
+			return err
+		}
+		ct = mw.FormDataContentType()
 	} else if reader, ok := params.(io.Reader); ok {
 		var buf bytes.Buffer
 		mw := multipart.NewWriter(&buf)
@@ -143,6 +173,9 @@ func (c *Client) doAPI(ctx context.Context, method string, uri string, params in
 		}
 	}
 	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
 	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
 	if params != nil {
 		req.Header.Set("Content-Type", ct)
@@ -154,9 +187,9 @@ func (c *Client) doAPI(ctx context.Context, method string, uri string, params in
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return parseAPIError("bad request", resp)
-	} else if res == nil {
+	} else if res == nil || resp.StatusCode == http.StatusNoContent {
 		return nil
 	} else if pg != nil {
 		if lh := resp.Header.Get("Link"); lh != "" {
@@ -257,6 +290,15 @@ type Toot struct {
 	SpoilerText string   `json:"spoiler_text"`
 	Visibility  string   `json:"visibility"`
 	ContentType string   `json:"content_type"`
+	Language    string   `json:"language"`
+
+	// Preview makes the instance render the status without posting it.
+	// Currently only supported by Pleroma.
+	Preview bool `json:"preview"`
+
+	// IdempotencyKey is sent as the Idempotency-Key header. Instances
+	// return the already created status for repeated keys.
+	IdempotencyKey string `json:"-"`
 }
 
 // Mention hold information for mention.
@@ -269,16 +311,17 @@ type Mention struct {
 
 // Tag hold information for tag.
 type Tag struct {
-	Name    string    `json:"name"`
-	URL     string    `json:"url"`
-	History []History `json:"history"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	History   []History `json:"history"`
+	Following bool      `json:"following"`
 }
 
 // History hold information for history.
 type History struct {
 	Day      string `json:"day"`
-	Uses     int64  `json:"uses"`
-	Accounts int64  `json:"accounts"`
+	Uses     Count  `json:"uses"`
+	Accounts Count  `json:"accounts"`
 }
 
 // Attachment hold information for attachment.
@@ -54,10 +54,26 @@ func (c *Client) ClearNotifications(ctx context.Context) error {
 	return c.doAPI(ctx, http.MethodPost, "/api/v1/notifications/clear", nil, nil, nil)
 }
 
-// ReadNotifications marks notifications as read
-// Currenly only works for Pleroma
+// DismissNotification dismisses a single notification. Servers which don't
+// know the per notification endpoint are sent the id as a parameter.
+func (c *Client) DismissNotification(ctx context.Context, id string) error {
+	err := c.doAPI(ctx, http.MethodPost, fmt.Sprintf("/api/v1/notifications/%s/dismiss", url.PathEscape(id)), nil, nil, nil)
+	if e, ok := err.(Error); ok && e.IsNotFound() {
+		params := url.Values{}
+		params.Set("id", id)
+		err = c.doAPI(ctx, http.MethodPost, "/api/v1/notifications/dismiss", params, nil, nil)
+	}
+	return err
+}
+
+// ReadNotifications marks notifications up to maxID as read. Servers other
+// than Pleroma only keep track of it with the notifications marker.
 func (c *Client) ReadNotifications(ctx context.Context, maxID string) error {
 	params := url.Values{}
 	params.Set("max_id", maxID)
-	return c.doAPI(ctx, http.MethodPost, "/api/v1/pleroma/notifications/read", params, nil, nil)
+	err := c.doAPI(ctx, http.MethodPost, "/api/v1/pleroma/notifications/read", params, nil, nil)
+	if isNotFound(err) {
+		return c.SetMarker(ctx, "notifications", maxID)
+	}
+	return err
 }
@@ -4,12 +4,22 @@ import (
 	"context"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 )
 
 // Report hold information for mastodon report.
 type Report struct {
-	ID          int64 `json:"id"`
-	ActionTaken bool  `json:"action_taken"`
+	ID            string     `json:"id"`
+	ActionTaken   bool       `json:"action_taken"`
+	ActionTakenAt *time.Time `json:"action_taken_at"`
+	Category      string     `json:"category"`
+	Comment       string     `json:"comment"`
+	Forwarded     bool       `json:"forwarded"`
+	CreatedAt     time.Time  `json:"created_at"`
+	StatusIDs     []string   `json:"status_ids"`
+	RuleIDs       []string   `json:"rule_ids"`
+	TargetAccount *Account   `json:"target_account"`
 }
 
 // GetReports return report of the current user.
@@ -22,14 +32,25 @@ func (c *Client) GetReports(ctx context.Context) ([]*Report, error) {
 	return reports, nil
 }
 
-// Report reports the report
-func (c *Client) Report(ctx context.Context, accountID string, ids []string, comment string) (*Report, error) {
+// Report reports the account to the moderators, along with the statuses ids.
+// category is one of "spam", "violation" or "other", and ruleIDs are the
+// violated rules of the instance. Reports of remote accounts are also sent
+// to the instance of the account if forward is true.
+func (c *Client) Report(ctx context.Context, accountID string, ids []string, comment string,
+	category string, ruleIDs []string, forward bool) (*Report, error) {
 	params := url.Values{}
 	params.Set("account_id", string(accountID))
 	for _, id := range ids {
 		params.Add("status_ids[]", string(id))
 	}
 	params.Set("comment", comment)
+	if len(category) > 0 {
+		params.Set("category", category)
+	}
+	for _, id := range ruleIDs {
+		params.Add("rule_ids[]", id)
+	}
+	params.Set("forward", strconv.FormatBool(forward))
 	var report Report
 	err := c.doAPI(ctx, http.MethodPost, "/api/v1/reports", params, &report, nil)
 	if err != nil {
@@ -1,9 +1,11 @@
 package mastodon
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -34,33 +36,35 @@ func (t *CreatedAt) UnmarshalJSON(d []byte) error {
 
 // Status is struct to hold status.
 type Status struct {
-	ID                 string       `json:"id"`
-	URI                string       `json:"uri"`
-	URL                string       `json:"url"`
-	Account            Account      `json:"account"`
-	InReplyToID        interface{}  `json:"in_reply_to_id"`
-	InReplyToAccountID interface{}  `json:"in_reply_to_account_id"`
-	Reblog             *Status      `json:"reblog"`
-	Content            string       `json:"content"`
-	CreatedAt          CreatedAt    `json:"created_at"`
-	Emojis             []Emoji      `json:"emojis"`
-	RepliesCount       int64        `json:"replies_count"`
-	ReblogsCount       int64        `json:"reblogs_count"`
-	FavouritesCount    int64        `json:"favourites_count"`
-	Reblogged          interface{}  `json:"reblogged"`
-	Favourited         interface{}  `json:"favourited"`
-	Muted              interface{}  `json:"muted"`
-	Sensitive          bool         `json:"sensitive"`
-	SpoilerText        string       `json:"spoiler_text"`
-	Visibility         string       `json:"visibility"`
-	MediaAttachments   []Attachment `json:"media_attachments"`
-	Mentions           []Mention    `json:"mentions"`
-	Tags               []Tag        `json:"tags"`
-	Application        Application  `json:"application"`
-	Language           string       `json:"language"`
-	Pinned             interface{}  `json:"pinned"`
-	Bookmarked         bool         `json:"bookmarked"`
-	Poll               *Poll        `json:"poll"`
+	ID                 string         `json:"id"`
+	URI                string         `json:"uri"`
+	URL                string         `json:"url"`
+	Account            Account        `json:"account"`
+	InReplyToID        interface{}    `json:"in_reply_to_id"`
+	InReplyToAccountID interface{}    `json:"in_reply_to_account_id"`
+	Reblog             *Status        `json:"reblog"`
+	Content            string         `json:"content"`
+	CreatedAt          CreatedAt      `json:"created_at"`
+	Emojis             []Emoji        `json:"emojis"`
+	RepliesCount       int64          `json:"replies_count"`
+	ReblogsCount       int64          `json:"reblogs_count"`
+	FavouritesCount    int64          `json:"favourites_count"`
+	Reblogged          interface{}    `json:"reblogged"`
+	Favourited         interface{}    `json:"favourited"`
+	Muted              interface{}    `json:"muted"`
+	Sensitive          bool           `json:"sensitive"`
+	SpoilerText        string         `json:"spoiler_text"`
+	Visibility         string         `json:"visibility"`
+	MediaAttachments   []Attachment   `json:"media_attachments"`
+	Mentions           []Mention      `json:"mentions"`
+	Tags               []Tag          `json:"tags"`
+	Application        Application    `json:"application"`
+	Language           string         `json:"language"`
+	Pinned             bool           `json:"pinned"`
+	Bookmarked         bool           `json:"bookmarked"`
+	Poll               *Poll          `json:"poll"`
+	Card               *Card          `json:"card"`
+	Filtered           []FilterResult `json:"filtered"`
 
 	// Custom fields
 	Pleroma       StatusPleroma          `json:"pleroma"`
@@ -68,6 +72,28 @@ type Status struct {
 	IDReplies     map[string][]ReplyInfo `json:"id_replies"`
 	IDNumbers     map[string]int         `json:"id_numbers"`
 	RetweetedByID string                 `json:"retweeted_by_id"`
+
+	// CollapsedBy describes the local mute rule which collapses the
+	// status.
+	CollapsedBy string `json:"collapsed_by"`
+
+	// AccountNote is the personal note on the author.
+	AccountNote string `json:"account_note"`
+}
+
+// Card hold information for mastodon card.
+type Card struct {
+	URL          string `json:"url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	Type         string `json:"type"`
+	AuthorName   string `json:"author_name"`
+	AuthorURL    string `json:"author_url"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	Image        string `json:"image"`
+	Width        int64  `json:"width"`
+	Height       int64  `json:"height"`
 }
 
 // Context hold information for mastodon context.
@@ -200,8 +226,10 @@ func (c *Client) GetTimelineHashtag(ctx context.Context, tag string, isLocal boo
 		params.Set("local", "t")
 	}
 
+	// The path is escaped by doAPI, escaping it here too would break tags
+	// with non-ASCII letters.
 	var statuses []*Status
-	err := c.doAPI(ctx, http.MethodGet, fmt.Sprintf("/api/v1/timelines/tag/%s", url.PathEscape(tag)), params, &statuses, pg)
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/timelines/tag/"+tag, params, &statuses, pg)
 	if err != nil {
 		return nil, err
 	}
@@ -259,9 +287,20 @@ func (c *Client) PostStatus(ctx context.Context, toot *Toot) (*Status, error) {
 	if toot.ContentType != "" {
 		params.Set("content_type", toot.ContentType)
 	}
+	if toot.Language != "" {
+		params.Set("language", toot.Language)
+	}
+	if toot.Preview {
+		params.Set("preview", "true")
+	}
+
+	var header http.Header
+	if toot.IdempotencyKey != "" {
+		header = http.Header{"Idempotency-Key": {toot.IdempotencyKey}}
+	}
 
 	var status Status
-	err := c.doAPI(ctx, http.MethodPost, "/api/v1/statuses", params, &status, nil)
+	err := c.doAPIWithHeader(ctx, http.MethodPost, "/api/v1/statuses", header, params, &status, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -295,28 +334,85 @@ func (c *Client) Search(ctx context.Context, q string, qType string, limit int,
 
 // UploadMedia upload a media attachment from a file.
 func (c *Client) UploadMedia(ctx context.Context, file string) (*Attachment, error) {
+	return c.uploadMedia(ctx, file)
+}
+
+// UploadMediaFromReader uploads a media attachment from a io.Reader.
+func (c *Client) UploadMediaFromReader(ctx context.Context, reader io.Reader) (*Attachment, error) {
+	return c.uploadMedia(ctx, reader)
+}
+
+// UploadMediaFromReader uploads a media attachment from a io.Reader.
+func (c *Client) UploadMediaFromMultipartFileHeader(ctx context.Context, fh *multipart.FileHeader) (*Attachment, error) {
+	return c.uploadMedia(ctx, fh)
+}
+
+// namedReader is a file upload whose content is read from r.
+type namedReader struct {
+	name string
+	r    io.Reader
+}
+
+// UploadMediaFromNamedReader uploads a media attachment from a io.Reader
+// under the given file name.
+func (c *Client) UploadMediaFromNamedReader(ctx context.Context, name string, r io.Reader) (*Attachment, error) {
+	return c.uploadMedia(ctx, namedReader{name, r})
+}
+
+// uploadMedia uploads a media attachment with the v2 API, which processes
+// large files asynchronously, and falls back to the v1 API on instances
+// which don't support it. The URL of the returned attachment is empty while
+// the attachment is being processed, see GetMedia.
+func (c *Client) uploadMedia(ctx context.Context, params interface{}) (*Attachment, error) {
+	// Readers have to be read again in case of a fallback.
+	var rs io.ReadSeeker
+	var err error
+	switch p := params.(type) {
+	case namedReader:
+		rs, err = readSeeker(p.r)
+		p.r = rs
+		params = p
+	case io.Reader:
+		rs, err = readSeeker(p)
+		params = rs
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	var attachment Attachment
-	err := c.doAPI(ctx, http.MethodPost, "/api/v1/media", file, &attachment, nil)
+	err = c.doAPI(ctx, http.MethodPost, "/api/v2/media", params, &attachment, nil)
+	if e, ok := err.(Error); ok && e.IsNotFound() {
+		if rs != nil {
+			_, err = rs.Seek(0, io.SeekStart)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = c.doAPI(ctx, http.MethodPost, "/api/v1/media", params, &attachment, nil)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &attachment, nil
 }
 
-// UploadMediaFromReader uploads a media attachment from a io.Reader.
-func (c *Client) UploadMediaFromReader(ctx context.Context, reader io.Reader) (*Attachment, error) {
-	var attachment Attachment
-	err := c.doAPI(ctx, http.MethodPost, "/api/v1/media", reader, &attachment, nil)
+func readSeeker(r io.Reader) (io.ReadSeeker, error) {
+	if rs, ok := r.(io.ReadSeeker); ok {
+		return rs, nil
+	}
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
-	return &attachment, nil
+	return bytes.NewReader(data), nil
 }
 
-// UploadMediaFromReader uploads a media attachment from a io.Reader.
-func (c *Client) UploadMediaFromMultipartFileHeader(ctx context.Context, fh *multipart.FileHeader) (*Attachment, error) {
+// GetMedia returns the media attachment specified by id. The URL of the
+// attachment is empty while it is being processed.
+func (c *Client) GetMedia(ctx context.Context, id string) (*Attachment, error) {
 	var attachment Attachment
-	err := c.doAPI(ctx, http.MethodPost, "/api/v1/media", fh, &attachment, nil)
+	err := c.doAPI(ctx, http.MethodGet, fmt.Sprintf("/api/v1/media/%s", url.PathEscape(id)), nil, &attachment, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -378,3 +474,25 @@ func (c *Client) Unbookmark(ctx context.Context, id string) (*Status, error) {
 	}
 	return &status, nil
 }
+
+// Pin pins status specified by id to the profile of the current user.
+func (c *Client) Pin(ctx context.Context, id string) (*Status, error) {
+	var status Status
+
+	err := c.doAPI(ctx, http.MethodPost, fmt.Sprintf("/api/v1/statuses/%s/pin", id), nil, &status, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Unpin unpins status specified by id.
+func (c *Client) Unpin(ctx context.Context, id string) (*Status, error) {
+	var status Status
+
+	err := c.doAPI(ctx, http.MethodPost, fmt.Sprintf("/api/v1/statuses/%s/unpin", id), nil, &status, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
@@ -4,13 +4,34 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"math/rand"
+	"mime"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"sync"
+	"time"
 )
 
+const (
+	// streamBufferSize is the number of events buffered for the reader
+	// of a stream. Reading from the server pauses while the buffer is full.
+	streamBufferSize = 64
+
+	// streamHeartbeatTimeout is the time after which a connection is
+	// considered dead if nothing has been received. Servers send
+	// heartbeats every 15 to 30 seconds.
+	streamHeartbeatTimeout = 90 * time.Second
+
+	streamMinBackoff = time.Second
+	streamMaxBackoff = 2 * time.Minute
+)
+
+var errHeartbeatTimeout = errors.New("stream heartbeat timed out")
+
 // UpdateEvent is struct for passing status event to app.
 type UpdateEvent struct {
 	Status *Status `json:"status"`
@@ -18,6 +39,13 @@ type UpdateEvent struct {
 
 func (e *UpdateEvent) event() {}
 
+// StatusUpdateEvent is struct for passing edited status event to app.
+type StatusUpdateEvent struct {
+	Status *Status `json:"status"`
+}
+
+func (e *StatusUpdateEvent) event() {}
+
 // NotificationEvent is struct for passing notification event to app.
 type NotificationEvent struct {
 	Notification *Notification `json:"notification"`
@@ -30,6 +58,31 @@ type DeleteEvent struct{ ID string }
 
 func (e *DeleteEvent) event() {}
 
+// FiltersChangedEvent is struct for passing filter change event to app.
+type FiltersChangedEvent struct{}
+
+func (e *FiltersChangedEvent) event() {}
+
+// ConversationEvent is struct for passing conversation event to app.
+type ConversationEvent struct {
+	Conversation *Conversation `json:"conversation"`
+}
+
+func (e *ConversationEvent) event() {}
+
+// AnnouncementEvent is struct for passing announcement event to app.
+type AnnouncementEvent struct {
+	Announcement *Announcement `json:"announcement"`
+}
+
+func (e *AnnouncementEvent) event() {}
+
+// AnnouncementDeleteEvent is struct for passing announcement deletion event
+// to app.
+type AnnouncementDeleteEvent struct{ ID string }
+
+func (e *AnnouncementDeleteEvent) event() {}
+
 // ErrorEvent is struct for passing errors to app.
 type ErrorEvent struct{ err error }
 
@@ -41,10 +94,70 @@ type Event interface {
 	event()
 }
 
-func handleReader(q chan Event, r io.Reader) error {
+// send passes e to the reader of q. It waits for the reader, so that no
+// events get lost, until ctx is canceled.
+func send(ctx context.Context, q chan Event, e Event) {
+	select {
+	case q <- e:
+	case <-ctx.Done():
+	}
+}
+
+// parseEvent decodes the payload of the event called name. Unknown events
+// are ignored.
+func parseEvent(name string, data string) (Event, error) {
+	var err error
+	switch name {
+	case "update":
+		var status Status
+		err = json.Unmarshal([]byte(data), &status)
+		if err == nil {
+			return &UpdateEvent{&status}, nil
+		}
+	case "status.update":
+		var status Status
+		err = json.Unmarshal([]byte(data), &status)
+		if err == nil {
+			return &StatusUpdateEvent{&status}, nil
+		}
+	case "notification":
+		var notification Notification
+		err = json.Unmarshal([]byte(data), &notification)
+		if err == nil {
+			return &NotificationEvent{&notification}, nil
+		}
+	case "delete":
+		return &DeleteEvent{ID: strings.TrimSpace(data)}, nil
+	case "filters_changed":
+		return &FiltersChangedEvent{}, nil
+	case "conversation":
+		var conversation Conversation
+		err = json.Unmarshal([]byte(data), &conversation)
+		if err == nil {
+			return &ConversationEvent{&conversation}, nil
+		}
+	case "announcement":
+		var announcement Announcement
+		err = json.Unmarshal([]byte(data), &announcement)
+		if err == nil {
+			return &AnnouncementEvent{&announcement}, nil
+		}
+	case "announcement.delete":
+		return &AnnouncementDeleteEvent{ID: strings.TrimSpace(data)}, nil
+	}
+	return nil, err
+}
+
+// handleReader reads server-sent events from r. alive is called whenever
+// something, including a heartbeat comment, has been received.
+func handleReader(ctx context.Context, q chan Event, r io.Reader,
+	alive func()) error {
+
 	var name string
 	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
 	for s.Scan() {
+		alive()
 		line := s.Text()
 		token := strings.SplitN(line, ":", 2)
 		if len(token) != 2 {
@@ -54,31 +167,35 @@ func handleReader(q chan Event, r io.Reader) error {
 		case "event":
 			name = strings.TrimSpace(token[1])
 		case "data":
-			var err error
-			switch name {
-			case "update":
-				var status Status
-				err = json.Unmarshal([]byte(token[1]), &status)
-				if err == nil {
-					q <- &UpdateEvent{&status}
-				}
-			case "notification":
-				var notification Notification
-				err = json.Unmarshal([]byte(token[1]), &notification)
-				if err == nil {
-					q <- &NotificationEvent{&notification}
-				}
-			case "delete":
-				q <- &DeleteEvent{ID: string(strings.TrimSpace(token[1]))}
-			}
+			e, err := parseEvent(name, token[1])
 			if err != nil {
-				q <- &ErrorEvent{err}
+				send(ctx, q, &ErrorEvent{err})
+			} else if e != nil {
+				send(ctx, q, e)
 			}
 		}
 	}
 	return s.Err()
 }
 
+// backoff returns the delay before the given reconnection attempt. The
+// delay grows exponentially and is randomized, so that clients which lost
+// their connections at the same time don't reconnect at the same time.
+func backoff(attempt int) time.Duration {
+	d := streamMaxBackoff
+	if attempt < 8 {
+		d = streamMinBackoff << uint(attempt)
+		if d > streamMaxBackoff {
+			d = streamMaxBackoff
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// errWebSocketRequired is returned when the server doesn't serve server-sent
+// events, which servers only supporting WebSocket streaming do.
+var errWebSocketRequired = errors.New("server requires websocket streaming")
+
 func (c *Client) streaming(ctx context.Context, p string, params url.Values) (chan Event, error) {
 	u, err := url.Parse(c.config.Server)
 	if err != nil {
@@ -87,46 +204,136 @@ func (c *Client) streaming(ctx context.Context, p string, params url.Values) (ch
 	u.Path = path.Join(u.Path, "/api/v1/streaming", p)
 	u.RawQuery = params.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
-	if err != nil {
-		return nil, err
+	// WebSocket clients select the stream with a parameter, e.g.,
+	// "public/local" becomes "public:local".
+	wsParams := url.Values{}
+	for k, v := range params {
+		wsParams[k] = v
 	}
-	req = req.WithContext(ctx)
-	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+	wsParams.Set("stream", strings.Replace(p, "/", ":", -1))
 
-	q := make(chan Event)
+	q := make(chan Event, streamBufferSize)
 	go func() {
 		defer close(q)
-		for {
+		var ws *url.URL
+		for attempt := 0; ; attempt++ {
+			var connected bool
+			var err error
+			if ws == nil {
+				connected, err = c.doStreaming(ctx, u.String(), q)
+				if err == errWebSocketRequired {
+					ws, err = c.webSocketURL(ctx, wsParams)
+					if err == nil {
+						attempt = -1
+						continue
+					}
+				}
+			} else {
+				connected, err = c.doWebSocket(ctx, ws.String(), q)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				send(ctx, q, &ErrorEvent{err})
+				// Reconnecting won't help if the token has been
+				// revoked.
+				var me Error
+				if errors.As(err, &me) && me.IsAuthError() {
+					return
+				}
+			}
+			if connected {
+				attempt = 0
+			}
 			select {
 			case <-ctx.Done():
 				return
-			default:
+			case <-time.After(backoff(attempt)):
 			}
-
-			c.doStreaming(req, q)
 		}
 	}()
 	return q, nil
 }
 
-func (c *Client) doStreaming(req *http.Request, q chan Event) {
-	resp, err := c.Do(req)
-	if err != nil {
-		q <- &ErrorEvent{err}
-		return
+// connect runs f with a context which is canceled if alive isn't called for
+// streamHeartbeatTimeout.
+func connect(ctx context.Context, f func(ctx context.Context, alive func()) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var once sync.Once
+	timeout := make(chan struct{})
+	t := time.AfterFunc(streamHeartbeatTimeout, func() {
+		once.Do(func() { close(timeout) })
+		cancel()
+	})
+	defer t.Stop()
+	err := f(ctx, func() {
+		t.Reset(streamHeartbeatTimeout)
+	})
+	select {
+	case <-timeout:
+		return errHeartbeatTimeout
+	default:
+		return err
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		q <- &ErrorEvent{parseAPIError("bad request", resp)}
-		return
-	}
+// doStreaming reads a stream of server-sent events until the connection is
+// closed. connected reports whether the stream has been opened.
+func (c *Client) doStreaming(ctx context.Context, u string, q chan Event) (connected bool, err error) {
+	err = connect(ctx, func(ctx context.Context, alive func()) error {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := c.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusNotFound, http.StatusMethodNotAllowed,
+			http.StatusUpgradeRequired:
+			return errWebSocketRequired
+		default:
+			return parseAPIError("bad request", resp)
+		}
+		mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if mt != "text/event-stream" {
+			return errWebSocketRequired
+		}
+
+		connected = true
+		return handleReader(ctx, q, resp.Body, alive)
+	})
+	return
+}
 
-	err = handleReader(q, resp.Body)
+// webSocketURL returns the URL of the WebSocket streaming endpoint, which
+// can be on a different host than the API.
+func (c *Client) webSocketURL(ctx context.Context, params url.Values) (*url.URL, error) {
+	base := c.config.Server
+	instance, err := c.GetInstance(ctx)
 	if err != nil {
-		q <- &ErrorEvent{err}
+		return nil, err
+	}
+	if s := instance.URLs["streaming_api"]; len(s) > 0 {
+		base = s
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
 	}
+	u.Path = path.Join(u.Path, "/api/v1/streaming")
+	u.RawQuery = params.Encode()
+	return u, nil
 }
 
 // StreamingUser return channel to read events on home.
@@ -164,3 +371,8 @@ func (c *Client) StreamingList(ctx context.Context, id string) (chan Event, erro
 
 	return c.streaming(ctx, "list", params)
 }
+
+// StreamingDirect return channel to read events on direct messages.
+func (c *Client) StreamingDirect(ctx context.Context) (chan Event, error) {
+	return c.streaming(ctx, "direct", nil)
+}
@@ -0,0 +1,97 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// The paths are escaped by doAPI, so tag names are used as they are.
+
+// FeaturedTag hold information for a tag featured on a profile.
+type FeaturedTag struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// GetTag return a tag along with whether it is followed.
+func (c *Client) GetTag(ctx context.Context, name string) (*Tag, error) {
+	var tag Tag
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/tags/"+name, nil, &tag, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// FollowTag makes the statuses with the tag show up in the home timeline.
+func (c *Client) FollowTag(ctx context.Context, name string) (*Tag, error) {
+	var tag Tag
+	err := c.doAPI(ctx, http.MethodPost, "/api/v1/tags/"+name+"/follow", nil, &tag, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// UnfollowTag stops following a tag.
+func (c *Client) UnfollowTag(ctx context.Context, name string) (*Tag, error) {
+	var tag Tag
+	err := c.doAPI(ctx, http.MethodPost, "/api/v1/tags/"+name+"/unfollow", nil, &tag, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetFollowedTags return the tags followed by the current user.
+func (c *Client) GetFollowedTags(ctx context.Context, pg *Pagination) ([]*Tag, error) {
+	var tags []*Tag
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/followed_tags", nil, &tags, pg)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetFeaturedTags return the tags featured on the profile of the current
+// user.
+func (c *Client) GetFeaturedTags(ctx context.Context) ([]*FeaturedTag, error) {
+	var tags []*FeaturedTag
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/featured_tags", nil, &tags, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetAccountFeaturedTags return the tags featured on the profile of an
+// account.
+func (c *Client) GetAccountFeaturedTags(ctx context.Context, id string) ([]*FeaturedTag, error) {
+	var tags []*FeaturedTag
+	err := c.doAPI(ctx, http.MethodGet, fmt.Sprintf("/api/v1/accounts/%s/featured_tags", url.PathEscape(id)), nil, &tags, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// FeatureTag features a tag on the profile of the current user.
+func (c *Client) FeatureTag(ctx context.Context, name string) (*FeaturedTag, error) {
+	params := url.Values{}
+	params.Set("name", name)
+
+	var tag FeaturedTag
+	err := c.doAPI(ctx, http.MethodPost, "/api/v1/featured_tags", params, &tag, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// UnfeatureTag removes a featured tag from the profile of the current user.
+func (c *Client) UnfeatureTag(ctx context.Context, id string) error {
+	return c.doAPI(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/featured_tags/%s", url.PathEscape(id)), nil, nil, nil)
+}
@@ -0,0 +1,110 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// TrendsLink hold information for a trending link.
+type TrendsLink struct {
+	Card
+	History []History `json:"history"`
+}
+
+// Suggestion hold information for a suggested account to follow.
+type Suggestion struct {
+	Source  string   `json:"source"`
+	Account *Account `json:"account"`
+}
+
+func offsetValues(offset int, limit int) url.Values {
+	params := url.Values{}
+	params.Set("offset", fmt.Sprint(offset))
+	params.Set("limit", fmt.Sprint(limit))
+	return params
+}
+
+// GetTrendingTags return the tags which are trending on the instance.
+// Servers which predate the trends API only have the first page of tags.
+func (c *Client) GetTrendingTags(ctx context.Context, offset int, limit int) ([]*Tag, error) {
+	var tags []*Tag
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/trends/tags", offsetValues(offset, limit), &tags, nil)
+	if isNotFound(err) {
+		if offset > 0 {
+			return nil, nil
+		}
+		params := url.Values{}
+		params.Set("limit", fmt.Sprint(limit))
+		err = c.doAPI(ctx, http.MethodGet, "/api/v1/trends", params, &tags, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetTrendingStatuses return the statuses which are trending on the
+// instance.
+func (c *Client) GetTrendingStatuses(ctx context.Context, offset int, limit int) ([]*Status, error) {
+	var statuses []*Status
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/trends/statuses", offsetValues(offset, limit), &statuses, nil)
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// GetTrendingLinks return the links which are trending on the instance.
+func (c *Client) GetTrendingLinks(ctx context.Context, offset int, limit int) ([]*TrendsLink, error) {
+	var links []*TrendsLink
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/trends/links", offsetValues(offset, limit), &links, nil)
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// GetDirectory return the accounts in the profile directory. order is
+// either "active" or "new".
+func (c *Client) GetDirectory(ctx context.Context, offset int, limit int, order string, isLocal bool) ([]*Account, error) {
+	params := offsetValues(offset, limit)
+	params.Set("order", order)
+	if isLocal {
+		params.Set("local", "true")
+	}
+
+	var accounts []*Account
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/directory", params, &accounts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetSuggestions return accounts suggested to follow. Servers without the
+// v2 API don't tell the source of the suggestions.
+func (c *Client) GetSuggestions(ctx context.Context, limit int) ([]*Suggestion, error) {
+	params := url.Values{}
+	params.Set("limit", fmt.Sprint(limit))
+
+	var suggestions []*Suggestion
+	err := c.doAPI(ctx, http.MethodGet, "/api/v2/suggestions", params, &suggestions, nil)
+	if isNotFound(err) {
+		var accounts []*Account
+		err = c.doAPI(ctx, http.MethodGet, "/api/v1/suggestions", params, &accounts, nil)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = make([]*Suggestion, len(accounts))
+		for i := range accounts {
+			suggestions[i] = &Suggestion{Account: accounts[i]}
+		}
+		return suggestions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
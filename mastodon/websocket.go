@@ -0,0 +1,255 @@
+package mastodon
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// This is a minimal client for the WebSocket protocol (RFC 6455), which is
+// only as complete as needed for receiving the messages of the streaming
+// API.
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+
+	wsMaxMessageSize = 1 << 20
+	wsGUID           = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+var (
+	errWebSocketHandshake = errors.New("websocket handshake failed")
+	errWebSocketProtocol  = errors.New("websocket protocol error")
+	errWebSocketTooLarge  = errors.New("websocket message too large")
+)
+
+type wsConn struct {
+	r  *bufio.Reader
+	rw io.ReadWriteCloser
+}
+
+// readFrame reads a single frame and returns its payload.
+func (ws *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var h [2]byte
+	_, err = io.ReadFull(ws.r, h[:])
+	if err != nil {
+		return
+	}
+	fin = h[0]&0x80 != 0
+	op = h[0] & 0x0f
+	masked := h[1]&0x80 != 0
+	n := uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		_, err = io.ReadFull(ws.r, b[:])
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		_, err = io.ReadFull(ws.r, b[:])
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if err != nil {
+		return
+	}
+	if n > wsMaxMessageSize {
+		err = errWebSocketTooLarge
+		return
+	}
+	var mask [4]byte
+	if masked {
+		_, err = io.ReadFull(ws.r, mask[:])
+		if err != nil {
+			return
+		}
+	}
+	payload = make([]byte, n)
+	_, err = io.ReadFull(ws.r, payload)
+	if err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// writeFrame writes a control frame. Frames sent by clients must be masked.
+func (ws *wsConn) writeFrame(op byte, payload []byte) error {
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	b := make([]byte, 6+len(payload))
+	b[0] = 0x80 | op
+	b[1] = 0x80 | byte(len(payload))
+	_, err := rand.Read(b[2:6])
+	if err != nil {
+		return err
+	}
+	for i := range payload {
+		b[6+i] = payload[i] ^ b[2+i%4]
+	}
+	_, err = ws.rw.Write(b)
+	return err
+}
+
+// readMessage reads the next text or binary message. Control frames are
+// handled on the way.
+func (ws *wsConn) readMessage(alive func()) ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		alive()
+		switch op {
+		case wsOpPing:
+			err = ws.writeFrame(wsOpPong, payload)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			ws.writeFrame(wsOpClose, nil)
+			return nil, io.EOF
+		case wsOpText, wsOpBinary, wsOpContinuation:
+		default:
+			return nil, errWebSocketProtocol
+		}
+		if len(msg)+len(payload) > wsMaxMessageSize {
+			return nil, errWebSocketTooLarge
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+func wsAccept(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+wsGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// dialWebSocket opens a WebSocket connection to u, which can have either a
+// ws(s) or http(s) scheme.
+func (c *Client) dialWebSocket(ctx context.Context, u string) (*wsConn, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	switch pu.Scheme {
+	case "ws":
+		pu.Scheme = "http"
+	case "wss":
+		pu.Scheme = "https"
+	}
+
+	var k [16]byte
+	_, err = rand.Read(k[:])
+	if err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(k[:])
+
+	req, err := http.NewRequest(http.MethodGet, pu.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		return nil, parseAPIError("bad request", resp)
+	}
+	rw, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok || !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {
+		resp.Body.Close()
+		return nil, errWebSocketHandshake
+	}
+	return &wsConn{r: bufio.NewReader(rw), rw: rw}, nil
+}
+
+// wsMessage is a message of the streaming API. The payload is a JSON
+// encoded string, which contains JSON itself for most events.
+type wsMessage struct {
+	Event   string `json:"event"`
+	Payload string `json:"payload"`
+}
+
+// doWebSocket reads the events of a WebSocket stream until the connection
+// is closed. connected reports whether the stream has been opened.
+func (c *Client) doWebSocket(ctx context.Context, u string, q chan Event) (connected bool, err error) {
+	err = connect(ctx, func(ctx context.Context, alive func()) error {
+		ws, err := c.dialWebSocket(ctx, u)
+		if err != nil {
+			return err
+		}
+		defer ws.rw.Close()
+
+		// Reads don't return when the context is canceled.
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				ws.rw.Close()
+			case <-done:
+			}
+		}()
+
+		connected = true
+		for {
+			b, err := ws.readMessage(alive)
+			if err != nil {
+				if ctx.Err() != nil || err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			var msg wsMessage
+			err = json.Unmarshal(b, &msg)
+			if err != nil {
+				send(ctx, q, &ErrorEvent{err})
+				continue
+			}
+			e, err := parseEvent(msg.Event, msg.Payload)
+			if err != nil {
+				send(ctx, q, &ErrorEvent{err})
+			} else if e != nil {
+				send(ctx, q, e)
+			}
+		}
+	})
+	return
+}
@@ -0,0 +1,178 @@
+// Package media prepares images for upload. Metadata is removed from JPEG,
+// PNG and WebP images and large JPEG and PNG images can be scaled down.
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+const (
+	jpegQuality = 90
+
+	// Images with more pixels are not decoded, so that a small file can't
+	// make the server allocate an excessive amount of memory.
+	maxPixels = 64 << 20
+)
+
+// Clean removes the metadata from an image. If maxSize is greater than 0,
+// images whose width or height exceeds maxSize are scaled down to fit. Data
+// of other types is returned as is.
+func Clean(data []byte, maxSize int) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8")):
+		out, orientation, err := stripJPEG(data)
+		if err != nil {
+			return nil, err
+		}
+		return transform(out, orientation, maxSize, encodeJPEG)
+	case bytes.HasPrefix(data, pngSignature):
+		out, orientation, err := stripPNG(data)
+		if err != nil {
+			return nil, err
+		}
+		return transform(out, orientation, maxSize, png.Encode)
+	case len(data) >= 12 && string(data[:4]) == "RIFF" &&
+		string(data[8:12]) == "WEBP":
+		// There is no WebP encoder in the standard library, so
+		// WebP images are never scaled.
+		return stripWebP(data)
+	}
+	return data, nil
+}
+
+func encodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+}
+
+// transform applies the orientation and scales the image down if necessary.
+// The image is only decoded and encoded again if it has to be changed.
+func transform(data []byte, orientation int, maxSize int,
+	encode func(w io.Writer, img image.Image) error) ([]byte, error) {
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	w, h := cfg.Width, cfg.Height
+	if orientation >= 5 {
+		w, h = h, w
+	}
+	scale := maxSize > 0 && (w > maxSize || h > maxSize)
+	if orientation == 1 && !scale {
+		return data, nil
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		// The image is uploaded as is, but it must still be shown
+		// upright.
+		return addOrientation(data, orientation), nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, cfg.Width, cfg.Height))
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+	img = orient(img, orientation)
+	if scale {
+		if w > h {
+			w, h = maxSize, h*maxSize/w
+		} else {
+			w, h = w*maxSize/h, maxSize
+		}
+		img = downscale(img, max(w, 1), max(h, 1))
+	}
+
+	var buf bytes.Buffer
+	err = encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// orient turns an image with the given EXIF orientation upright.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := sw, sh
+	if orientation >= 5 {
+		dw, dh = sh, sw
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = sw-1-x, y
+			case 3:
+				sx, sy = sw-1-x, sh-1-y
+			case 4:
+				sx, sy = x, sh-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, sh-1-x
+			case 7:
+				sx, sy = sw-1-y, sh-1-x
+			case 8:
+				sx, sy = sw-1-y, x
+			}
+			si := src.PixOffset(sx, sy)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// downscale scales an image down by averaging the source pixels covered by
+// each destination pixel.
+func downscale(src *image.RGBA, dw, dh int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, (y+1)*sh/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, (x+1)*sw/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					sum[0] += int(src.Pix[i])
+					sum[1] += int(src.Pix[i+1])
+					sum[2] += int(src.Pix[i+2])
+					sum[3] += int(src.Pix[i+3])
+					i += 4
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			di := dst.PixOffset(x, y)
+			for k := range sum {
+				dst.Pix[di+k] = uint8(sum[k] / n)
+			}
+		}
+	}
+	return dst
+}
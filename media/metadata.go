@@ -0,0 +1,249 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var errInvalidImage = errors.New("invalid image")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// stripJPEG removes the APP1 (EXIF and XMP), APP13 (IPTC) and comment
+// segments of a JPEG image. The EXIF orientation is returned, as it is lost
+// along with the rest of the EXIF data.
+func stripJPEG(b []byte) (out []byte, orientation int, err error) {
+	if len(b) < 4 || b[0] != 0xff || b[1] != 0xd8 {
+		return nil, 0, errInvalidImage
+	}
+	orientation = 1
+	out = make([]byte, 0, len(b))
+	out = append(out, b[:2]...)
+	i := 2
+	for {
+		if i >= len(b) || b[i] != 0xff {
+			return nil, 0, errInvalidImage
+		}
+		// Markers can be preceded by any number of fill bytes.
+		for i < len(b) && b[i] == 0xff {
+			i++
+		}
+		if i >= len(b) {
+			return nil, 0, errInvalidImage
+		}
+		marker := b[i]
+		i++
+		if marker == 0xd9 {
+			out = append(out, 0xff, marker)
+			return out, orientation, nil
+		}
+		if marker == 0x01 || marker >= 0xd0 && marker <= 0xd7 {
+			out = append(out, 0xff, marker)
+			continue
+		}
+		if i+2 > len(b) {
+			return nil, 0, errInvalidImage
+		}
+		n := int(binary.BigEndian.Uint16(b[i:]))
+		if n < 2 || i+n > len(b) {
+			return nil, 0, errInvalidImage
+		}
+		seg := b[i+2 : i+n]
+		switch marker {
+		case 0xe1:
+			if bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+				if o := exifOrientation(seg[6:]); o > 0 {
+					orientation = o
+				}
+			}
+		case 0xed, 0xfe:
+		case 0xda:
+			// The entropy coded data follows the start of scan
+			// header and contains no metadata.
+			out = append(out, 0xff, marker)
+			return append(out, b[i:]...), orientation, nil
+		default:
+			out = append(out, 0xff, marker)
+			out = append(out, b[i:i+n]...)
+		}
+		i += n
+	}
+}
+
+// stripPNG removes the textual, EXIF and modification time chunks of a PNG
+// image. XMP is stored in iTXt chunks.
+func stripPNG(b []byte) (out []byte, orientation int, err error) {
+	if !bytes.HasPrefix(b, pngSignature) {
+		return nil, 0, errInvalidImage
+	}
+	orientation = 1
+	out = make([]byte, 0, len(b))
+	out = append(out, pngSignature...)
+	i := len(pngSignature)
+	for i < len(b) {
+		if i+8 > len(b) {
+			return nil, 0, errInvalidImage
+		}
+		n := int(binary.BigEndian.Uint32(b[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(b) || end < i {
+			return nil, 0, errInvalidImage
+		}
+		typ := string(b[i+4 : i+8])
+		switch typ {
+		case "eXIf":
+			if o := exifOrientation(b[i+8 : i+8+n]); o > 0 {
+				orientation = o
+			}
+		case "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, b[i:end]...)
+		}
+		i = end
+		if typ == "IEND" {
+			break
+		}
+	}
+	return out, orientation, nil
+}
+
+// stripWebP removes the EXIF and XMP chunks of a WebP image and clears the
+// corresponding flags of the extended header.
+func stripWebP(b []byte) (out []byte, err error) {
+	if len(b) < 12 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return nil, errInvalidImage
+	}
+	size := int(binary.LittleEndian.Uint32(b[4:])) + 8
+	if size < 12 || size > len(b) {
+		return nil, errInvalidImage
+	}
+	b = b[:size]
+	out = make([]byte, 0, len(b))
+	out = append(out, b[:12]...)
+	vp8x := -1
+	i := 12
+	for i < len(b) {
+		if i+8 > len(b) {
+			return nil, errInvalidImage
+		}
+		n := int(binary.LittleEndian.Uint32(b[i+4:]))
+		end := i + 8 + n + n&1
+		if n < 0 || end > len(b) || end < i {
+			return nil, errInvalidImage
+		}
+		switch string(b[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			if n < 1 {
+				return nil, errInvalidImage
+			}
+			vp8x = len(out) + 8
+			fallthrough
+		default:
+			out = append(out, b[i:end]...)
+		}
+		i = end
+	}
+	if vp8x >= 0 {
+		out[vp8x] &^= 0x08 | 0x04
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
+
+// exifOrientation returns the orientation tag of the first image file
+// directory of the TIFF structured EXIF data, or 0 if there is none.
+func exifOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 0
+	}
+	var bo binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 0
+	}
+	off := int(bo.Uint32(b[4:]))
+	if off < 8 || off+2 > len(b) {
+		return 0
+	}
+	n := int(bo.Uint16(b[off:]))
+	for j := 0; j < n; j++ {
+		e := off + 2 + j*12
+		if e+12 > len(b) {
+			return 0
+		}
+		if bo.Uint16(b[e:]) == 0x0112 {
+			o := int(bo.Uint16(b[e+8:]))
+			if o < 1 || o > 8 {
+				return 0
+			}
+			return o
+		}
+	}
+	return 0
+}
+
+// orientationEXIF returns TIFF structured EXIF data which only holds the
+// orientation tag.
+func orientationEXIF(orientation int) []byte {
+	b := make([]byte, 26)
+	copy(b, "MM\x00\x2a")
+	binary.BigEndian.PutUint32(b[4:], 8)
+	binary.BigEndian.PutUint16(b[8:], 1)
+	binary.BigEndian.PutUint16(b[10:], 0x0112)
+	binary.BigEndian.PutUint16(b[12:], 3)
+	binary.BigEndian.PutUint32(b[14:], 1)
+	binary.BigEndian.PutUint16(b[18:], uint16(orientation))
+	return b
+}
+
+// addOrientation adds the orientation to a JPEG or PNG image whose metadata
+// has been removed, for images which are too large to be turned upright.
+func addOrientation(b []byte, orientation int) []byte {
+	if orientation < 2 || orientation > 8 {
+		return b
+	}
+	exif := orientationEXIF(orientation)
+	var seg []byte
+	var i int
+	switch {
+	case bytes.HasPrefix(b, []byte("\xff\xd8")):
+		// The APP1 segment directly follows the start of image.
+		seg = make([]byte, 10, 10+len(exif))
+		seg[0], seg[1] = 0xff, 0xe1
+		binary.BigEndian.PutUint16(seg[2:], uint16(8+len(exif)))
+		copy(seg[4:], "Exif\x00\x00")
+		seg = append(seg, exif...)
+		i = 2
+	case bytes.HasPrefix(b, pngSignature):
+		// The eXIf chunk directly follows the IHDR chunk, which is
+		// always the first one.
+		i = len(pngSignature)
+		if i+8 > len(b) {
+			return b
+		}
+		i += 12 + int(binary.BigEndian.Uint32(b[i:]))
+		if i > len(b) {
+			return b
+		}
+		seg = make([]byte, 8, 12+len(exif))
+		binary.BigEndian.PutUint32(seg, uint32(len(exif)))
+		copy(seg[4:], "eXIf")
+		seg = append(seg, exif...)
+		seg = seg[:len(seg)+4]
+		binary.BigEndian.PutUint32(seg[len(seg)-4:],
+			crc32.ChecksumIEEE(seg[4:len(seg)-4]))
+	default:
+		return b
+	}
+	out := make([]byte, 0, len(b)+len(seg))
+	out = append(out, b[:i]...)
+	out = append(out, seg...)
+	return append(out, b[i:]...)
+}
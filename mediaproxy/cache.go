@@ -0,0 +1,173 @@
+package mediaproxy
+
+import (
+	"bufio"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errCacheMiss = errors.New("cache miss")
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// Cache is a disk cache of fetched resources which drops the least recently
+// used resources once the total size exceeds its limit. Every resource is
+// stored in a file named after the hash of its URL, which starts with a line
+// containing the media type.
+type Cache struct {
+	dir     string
+	maxSize int64
+	m       sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+func NewCache(dir string, maxSize int64) (c *Cache, err error) {
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	c = &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+
+	// The modification time of a file is updated whenever it is read,
+	// so the order of use survives restarts.
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	for _, fi := range infos {
+		if fi.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(fi.Name(), ".tmp") {
+			os.Remove(filepath.Join(dir, fi.Name()))
+			continue
+		}
+		e := &cacheEntry{key: fi.Name(), size: fi.Size()}
+		c.entries[e.key] = c.lru.PushBack(e)
+		c.size += e.size
+	}
+	c.evict()
+	return c, nil
+}
+
+func cacheKey(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return hex.EncodeToString(sum[:])
+}
+
+type cacheFile struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// Open returns the cached resource of u along with its media type and size.
+func (c *Cache) Open(u string) (r io.ReadCloser, mediaType string,
+	size int64, err error) {
+
+	key := cacheKey(u)
+	c.m.Lock()
+	el, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(el)
+		size = el.Value.(*cacheEntry).size
+	}
+	c.m.Unlock()
+	if !ok {
+		return nil, "", 0, errCacheMiss
+	}
+
+	path := filepath.Join(c.dir, key)
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	br := bufio.NewReader(f)
+	mediaType, err = br.ReadString('\n')
+	if err != nil {
+		f.Close()
+		return
+	}
+	size -= int64(len(mediaType))
+	mediaType = strings.TrimSuffix(mediaType, "\n")
+	return cacheFile{br, f}, mediaType, size, nil
+}
+
+// Put adds the resource of u to the cache.
+func (c *Cache) Put(u string, mediaType string, data []byte) (err error) {
+	size := int64(len(mediaType) + 1 + len(data))
+	if size > c.maxSize || strings.Contains(mediaType, "\n") {
+		return nil
+	}
+	f, err := ioutil.TempFile(c.dir, "*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = f.WriteString(mediaType + "\n")
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+
+	key := cacheKey(u)
+	c.m.Lock()
+	defer c.m.Unlock()
+	err = os.Rename(f.Name(), filepath.Join(c.dir, key))
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.size -= el.Value.(*cacheEntry).size
+		c.lru.Remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: size})
+	c.size += size
+	c.evict()
+	return nil
+}
+
+func (c *Cache) evict() {
+	for c.size > c.maxSize {
+		el := c.lru.Back()
+		if el == nil {
+			return
+		}
+		e := el.Value.(*cacheEntry)
+		os.Remove(filepath.Join(c.dir, e.key))
+		c.lru.Remove(el)
+		delete(c.entries, e.key)
+		c.size -= e.size
+	}
+}
@@ -0,0 +1,163 @@
+package mediaproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	errInvalidMedia   = errors.New("invalid media")
+	errMediaTooLarge  = errors.New("media too large")
+	errPrivateAddress = errors.New("address is not public")
+)
+
+var privateNets []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8",
+		"169.254.0.0/16", "172.16.0.0/12", "192.0.0.0/24",
+		"192.168.0.0/16", "198.18.0.0/15", "::1/128", "fc00::/7",
+		"fe80::/10",
+	} {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		privateNets = append(privateNets, n)
+	}
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return false
+	}
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkPublicAddress is called after the host name has been resolved, so it
+// also catches host names which resolve to addresses of the local network.
+func checkPublicAddress(network string, address string,
+	_ syscall.RawConn) error {
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// Client fetches third party resources on behalf of users. It refuses to
+// connect to the local network, as the URLs come from untrusted sources.
+var Client = &http.Client{
+	Timeout: 5 * time.Minute,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: checkPublicAddress,
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConns:          16,
+		IdleConnTimeout:       90 * time.Second,
+	},
+}
+
+// IsImage accepts images other than SVG images, which can contain scripts.
+func IsImage(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "image/") &&
+		mediaType != "image/svg+xml"
+}
+
+// IsMedia accepts the media types of attachments, avatars and emojis.
+func IsMedia(mediaType string) bool {
+	return IsImage(mediaType) || strings.HasPrefix(mediaType, "video/") ||
+		strings.HasPrefix(mediaType, "audio/")
+}
+
+func writeHeader(w http.ResponseWriter, mediaType string, size int64) {
+	w.Header().Set("Content-Type", mediaType)
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+}
+
+// Fetch streams the resource at u to w. Only resources of an accepted media
+// type which aren't larger than maxSize are served. The resource is added to
+// the cache, if there is one.
+func Fetch(ctx context.Context, w http.ResponseWriter, u string,
+	maxSize int64, accept func(mediaType string) bool, cache *Cache) error {
+
+	pu, err := url.Parse(u)
+	if err != nil || pu.Scheme != "http" && pu.Scheme != "https" {
+		return errInvalidMedia
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	resp, err := Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("media: " + resp.Status)
+	}
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !accept(mt) {
+		return errInvalidMedia
+	}
+	if resp.ContentLength > maxSize {
+		return errMediaTooLarge
+	}
+
+	var buf *bytes.Buffer
+	var r io.Reader = io.LimitReader(resp.Body, maxSize+1)
+	if cache != nil && resp.ContentLength <= cache.maxSize {
+		buf = new(bytes.Buffer)
+		r = io.TeeReader(r, buf)
+	}
+	writeHeader(w, mt, resp.ContentLength)
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+	// The response has already been sent, but at least the client won't
+	// receive more data and nothing gets cached.
+	if n > maxSize {
+		return errMediaTooLarge
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return io.ErrUnexpectedEOF
+	}
+	// The media has been served even if it can't be cached.
+	if buf != nil {
+		cache.Put(u, mt, buf.Bytes())
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Package mediaproxy loads remote media through bloat, so that the servers
+// hosting it don't see the IP addresses of users.
+package mediaproxy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	Path          = "/proxy/media"
+	CardImagePath = "/proxy/cardimage"
+
+	// Images of link cards are only shown as thumbnails.
+	cardImageMaxSize = 4 << 20
+)
+
+var errInvalidSignature = errors.New("invalid signature")
+
+// Proxy serves remote media. Only URLs which have been signed by URL or
+// CardImageURL are served, so that it can't be used as an open proxy.
+type Proxy struct {
+	key     []byte
+	media   bool
+	hosts   []string
+	maxSize int64
+	cache   *Cache
+}
+
+// New creates a Proxy which serves images of link cards and, if media is
+// true, other media of up to maxSize bytes. A random key is used if key is
+// empty. If hosts isn't empty, only media from these hosts and their
+// subdomains is served, as link cards are from third party sites anyway.
+// The cache is optional.
+func New(key string, media bool, hosts []string, maxSize int64,
+	cache *Cache) (*Proxy, error) {
+
+	k := []byte(key)
+	if len(k) < 1 {
+		k = make([]byte, 32)
+		_, err := rand.Read(k)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Proxy{
+		key:     k,
+		media:   media,
+		hosts:   hosts,
+		maxSize: maxSize,
+		cache:   cache,
+	}, nil
+}
+
+func (p *Proxy) sign(u string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(u))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:18])
+}
+
+func isRemote(u string) bool {
+	return strings.HasPrefix(u, "https://") ||
+		strings.HasPrefix(u, "http://")
+}
+
+// URL returns the proxy URL of u. URLs are returned as is if p is nil or
+// doesn't proxy media.
+func (p *Proxy) URL(u string) string {
+	if p == nil || !p.media || !isRemote(u) {
+		return u
+	}
+	return Path + "?url=" + url.QueryEscape(u) + "&sig=" + p.sign(u)
+}
+
+// CardImageURL returns the proxy URL of the image of a link card. The
+// signature differs from the one of URL, so that the URL can't be used to
+// load other media. URLs are returned as is if p is nil.
+func (p *Proxy) CardImageURL(u string) string {
+	if p == nil || !isRemote(u) {
+		return u
+	}
+	return CardImagePath + "?url=" + url.QueryEscape(u) + "&sig=" +
+		p.sign("card:"+u)
+}
+
+func (p *Proxy) allowed(u string) bool {
+	if len(p.hosts) < 1 {
+		return true
+	}
+	pu, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(pu.Hostname())
+	for _, h := range p.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Serve writes the media at u to w, if sig is the signature of u.
+func (p *Proxy) Serve(ctx context.Context, w http.ResponseWriter, u string,
+	sig string) error {
+
+	if !p.media || !hmac.Equal([]byte(sig), []byte(p.sign(u))) {
+		return errInvalidSignature
+	}
+	if !p.allowed(u) {
+		return errInvalidMedia
+	}
+	return p.serve(ctx, w, u, p.maxSize, IsMedia)
+}
+
+// ServeCardImage writes the image at u to w, if sig is the signature of u
+// returned by CardImageURL.
+func (p *Proxy) ServeCardImage(ctx context.Context, w http.ResponseWriter,
+	u string, sig string) error {
+
+	if !hmac.Equal([]byte(sig), []byte(p.sign("card:"+u))) {
+		return errInvalidSignature
+	}
+	return p.serve(ctx, w, u, cardImageMaxSize, IsImage)
+}
+
+func (p *Proxy) serve(ctx context.Context, w http.ResponseWriter, u string,
+	maxSize int64, accept func(mediaType string) bool) error {
+
+	if p.cache != nil {
+		r, mt, size, err := p.cache.Open(u)
+		// The same URL may have been cached as another kind of media.
+		if err == nil && accept(mt) && size <= maxSize {
+			defer r.Close()
+			writeHeader(w, mt, size)
+			_, err = io.Copy(w, r)
+			return err
+		}
+		if err == nil {
+			r.Close()
+		}
+	}
+	return Fetch(ctx, w, u, maxSize, accept, p.cache)
+}
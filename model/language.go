@@ -0,0 +1,66 @@
+package model
+
+type Language struct {
+	Code string
+	Name string
+}
+
+// Languages lists the ISO 639-1 languages which can be selected for posts.
+var Languages = []Language{
+	{"ar", "العربية"},
+	{"bg", "Български"},
+	{"bn", "বাংলা"},
+	{"ca", "Català"},
+	{"cs", "Čeština"},
+	{"cy", "Cymraeg"},
+	{"da", "Dansk"},
+	{"de", "Deutsch"},
+	{"el", "Ελληνικά"},
+	{"en", "English"},
+	{"eo", "Esperanto"},
+	{"es", "Español"},
+	{"et", "Eesti"},
+	{"eu", "Euskara"},
+	{"fa", "فارسی"},
+	{"fi", "Suomi"},
+	{"fr", "Français"},
+	{"ga", "Gaeilge"},
+	{"gd", "Gàidhlig"},
+	{"gl", "Galego"},
+	{"he", "עברית"},
+	{"hi", "हिन्दी"},
+	{"hr", "Hrvatski"},
+	{"hu", "Magyar"},
+	{"hy", "Հայերեն"},
+	{"id", "Bahasa Indonesia"},
+	{"is", "Íslenska"},
+	{"it", "Italiano"},
+	{"ja", "日本語"},
+	{"ka", "ქართული"},
+	{"kk", "Қазақша"},
+	{"ko", "한국어"},
+	{"la", "Latina"},
+	{"lt", "Lietuvių"},
+	{"lv", "Latviešu"},
+	{"ms", "Bahasa Melayu"},
+	{"nl", "Nederlands"},
+	{"no", "Norsk"},
+	{"oc", "Occitan"},
+	{"pl", "Polski"},
+	{"pt", "Português"},
+	{"ro", "Română"},
+	{"ru", "Русский"},
+	{"sk", "Slovenčina"},
+	{"sl", "Slovenščina"},
+	{"sq", "Shqip"},
+	{"sr", "Српски"},
+	{"sv", "Svenska"},
+	{"ta", "தமிழ்"},
+	{"th", "ไทย"},
+	{"tl", "Tagalog"},
+	{"tr", "Türkçe"},
+	{"uk", "Українська"},
+	{"ur", "اردو"},
+	{"vi", "Tiếng Việt"},
+	{"zh", "中文"},
+}
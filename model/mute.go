@@ -0,0 +1,47 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// MuteRule hides or collapses the statuses which match all of its
+// predicates. Rules are applied by bloat, so they work with any instance.
+type MuteRule struct {
+	ID         string    `json:"id"`
+	Pattern    string    `json:"pattern,omitempty"`
+	Account    string    `json:"account,omitempty"`
+	Domain     string    `json:"domain,omitempty"`
+	BoostsFrom string    `json:"boosts_from,omitempty"`
+	NoMedia    bool      `json:"no_media,omitempty"`
+	Action     string    `json:"action"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IsEmpty reports whether r has no predicates, in which case it would match
+// every status.
+func (r *MuteRule) IsEmpty() bool {
+	return len(r.Pattern) < 1 && len(r.Account) < 1 && len(r.Domain) < 1 &&
+		len(r.BoostsFrom) < 1 && !r.NoMedia
+}
+
+// String describes the predicates of r.
+func (r *MuteRule) String() string {
+	var p []string
+	if len(r.Pattern) > 0 {
+		p = append(p, "/"+r.Pattern+"/")
+	}
+	if len(r.Account) > 0 {
+		p = append(p, "by @"+r.Account)
+	}
+	if len(r.Domain) > 0 {
+		p = append(p, "from "+r.Domain)
+	}
+	if len(r.BoostsFrom) > 0 {
+		p = append(p, "retweeted by @"+r.BoostsFrom)
+	}
+	if r.NoMedia {
+		p = append(p, "without media")
+	}
+	return strings.Join(p, " ")
+}
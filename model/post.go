@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type PostFormat struct {
 	Name string
 	Type string
@@ -8,8 +10,14 @@ type PostFormat struct {
 type PostContext struct {
 	DefaultVisibility string
 	DefaultFormat     string
+	DefaultContent    string
+	DefaultNSFW       bool
+	DefaultLanguage   string
+	IdempotencyKey    string
+	DraftID           string
 	ReplyContext      *ReplyContext
 	Formats           []PostFormat
+	Languages         []Language
 }
 
 type ReplyContext struct {
@@ -19,3 +27,17 @@ type ReplyContext struct {
 	ReplyContent    string
 	ForceVisibility bool
 }
+
+// Draft is an unsent post which is kept on the server, so that it can be
+// resumed later.
+type Draft struct {
+	ID            string    `json:"id"`
+	Content       string    `json:"content"`
+	Format        string    `json:"format,omitempty"`
+	Visibility    string    `json:"visibility,omitempty"`
+	IsNSFW        bool      `json:"nsfw,omitempty"`
+	Language      string    `json:"language,omitempty"`
+	InReplyToID   string    `json:"reply_to_id,omitempty"`
+	InReplyToName string    `json:"reply_to_name,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -8,6 +8,7 @@ type Session struct {
 	ClientSecret string   `json:"cs,omitempty"`
 	AccessToken  string   `json:"at,omitempty"`
 	CSRFToken    string   `json:"csrf,omitempty"`
+	Language     string   `json:"lang,omitempty"`
 	Settings     Settings `json:"sett,omitempty"`
 }
 
@@ -15,6 +16,15 @@ func (s Session) IsLoggedIn() bool {
 	return len(s.AccessToken) > 0
 }
 
+// DefaultLanguage returns the language of new posts, which is either set in
+// settings or taken from the account.
+func (s Session) DefaultLanguage() string {
+	if len(s.Settings.DefaultLanguage) > 0 {
+		return s.Settings.DefaultLanguage
+	}
+	return s.Language
+}
+
 type Settings struct {
 	DefaultVisibility     string `json:"dv,omitempty"`
 	DefaultFormat         string `json:"df,omitempty"`
@@ -28,6 +38,12 @@ type Settings struct {
 	AntiDopamineMode      bool   `json:"adm,omitempty"`
 	HideUnsupportedNotifs bool   `json:"hun,omitempty"`
 	CSS                   string `json:"css,omitempty"`
+	KeepOriginalImages    bool   `json:"koi,omitempty"`
+	DefaultLanguage       string `json:"dl,omitempty"`
+	ProxyCardImages       bool   `json:"pci,omitempty"`
+	ReduceMotion          bool   `json:"rm,omitempty"`
+	NestedThreads         bool   `json:"nt,omitempty"`
+	ShowAccountNotes      bool   `json:"san,omitempty"`
 }
 
 func NewSettings() *Settings {
@@ -44,5 +60,11 @@ func NewSettings() *Settings {
 		AntiDopamineMode:      false,
 		HideUnsupportedNotifs: false,
 		CSS:                   "",
+		KeepOriginalImages:    false,
+		DefaultLanguage:       "",
+		ProxyCardImages:       false,
+		ReduceMotion:          false,
+		NestedThreads:         false,
+		ShowAccountNotes:      false,
 	}
 }
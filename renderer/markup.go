@@ -0,0 +1,204 @@
+package renderer
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	urlRE        = regexp.MustCompile(`https?://[^\s<>"]+[^\s<>".,:;!?)\]'*_~]`)
+	linkRE       = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^\s)]+)\)`)
+	strongRE     = regexp.MustCompile(`(\*\*|__)([^\s*_](?:.*?[^\s*_])?)(\*\*|__)`)
+	emRE         = regexp.MustCompile(`(^|[^\w*])[*_]([^\s*_](?:[^*_]*?[^\s*_])?)[*_]([^\w*]|$)`)
+	strikeRE     = regexp.MustCompile(`~~([^\s~](?:.*?[^\s~])?)~~`)
+	headingRE    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	orderedRE    = regexp.MustCompile(`^\d+[.)]\s+`)
+	unorderedRE  = regexp.MustCompile(`^[-*+]\s+`)
+	placeholderR = strings.NewReplacer("\x00", "", "\x01", "")
+)
+
+// RenderPlainText renders text the way instances render plain text posts:
+// the text is escaped, links are made clickable, blank lines separate
+// paragraphs and other line breaks are kept.
+func RenderPlainText(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var paras []string
+	for _, p := range strings.Split(src, "\n\n") {
+		p = strings.Trim(p, "\n")
+		if len(p) < 1 {
+			continue
+		}
+		lines := strings.Split(p, "\n")
+		for i := range lines {
+			lines[i] = linkify(html.EscapeString(lines[i]))
+		}
+		paras = append(paras, "<p>"+strings.Join(lines, "<br/>")+"</p>")
+	}
+	return strings.Join(paras, "")
+}
+
+// RenderMarkdown renders a commonly used subset of Markdown: headings,
+// quotes, lists, fenced code blocks, code spans, emphasis, strikethrough
+// and links. Like Pleroma, single line breaks are kept as hard breaks.
+// Raw HTML is always escaped.
+func RenderMarkdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	lines := strings.Split(src, "\n")
+
+	var b strings.Builder
+	var para, quote []string
+	var list []string
+	var listTag string
+
+	flushPara := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + strings.Join(para, "<br/>") + "</p>")
+			para = nil
+		}
+	}
+	flushQuote := func() {
+		if len(quote) > 0 {
+			b.WriteString("<blockquote>" +
+				RenderMarkdown(strings.Join(quote, "\n")) +
+				"</blockquote>")
+			quote = nil
+		}
+	}
+	flushList := func() {
+		if len(list) > 0 {
+			b.WriteString("<" + listTag + ">")
+			for _, item := range list {
+				b.WriteString("<li>" + item + "</li>")
+			}
+			b.WriteString("</" + listTag + ">")
+			list = nil
+		}
+	}
+	flush := func() {
+		flushPara()
+		flushQuote()
+		flushList()
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+					break
+				}
+				code = append(code, html.EscapeString(lines[i]))
+			}
+			b.WriteString("<pre><code>" + strings.Join(code, "\n") +
+				"</code></pre>")
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ">") {
+			flushPara()
+			flushList()
+			quote = append(quote, strings.TrimPrefix(
+				strings.TrimPrefix(trimmed, ">"), " "))
+			continue
+		}
+		flushQuote()
+
+		if len(trimmed) < 1 {
+			flush()
+			continue
+		}
+
+		if m := headingRE.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			tag := "h" + strconv.Itoa(len(m[1]))
+			b.WriteString("<" + tag + ">" + renderInline(m[2]) +
+				"</" + tag + ">")
+			continue
+		}
+
+		var tag string
+		var loc []int
+		if loc = unorderedRE.FindStringIndex(trimmed); loc != nil {
+			tag = "ul"
+		} else if loc = orderedRE.FindStringIndex(trimmed); loc != nil {
+			tag = "ol"
+		}
+		if loc != nil {
+			flushPara()
+			if listTag != tag {
+				flushList()
+			}
+			listTag = tag
+			list = append(list, renderInline(trimmed[loc[1]:]))
+			continue
+		}
+		flushList()
+
+		para = append(para, renderInline(line))
+	}
+	flush()
+	return b.String()
+}
+
+// renderInline renders the inline elements of a single line. Code spans
+// are cut out first so that their content is left untouched.
+func renderInline(s string) string {
+	parts := strings.Split(s, "`")
+	var b strings.Builder
+	for i, p := range parts {
+		if i%2 == 1 && i < len(parts)-1 {
+			b.WriteString("<code>" + html.EscapeString(p) + "</code>")
+			continue
+		}
+		if i%2 == 1 {
+			b.WriteString("`")
+		}
+		b.WriteString(renderSpan(p))
+	}
+	return b.String()
+}
+
+func renderSpan(s string) string {
+	s = placeholderR.Replace(s)
+
+	// Links are replaced by placeholders so that emphasis markers within
+	// them are not processed.
+	var links []string
+	hold := func(l string) string {
+		links = append(links, l)
+		return "\x00" + strconv.Itoa(len(links)-1) + "\x01"
+	}
+	s = linkRE.ReplaceAllStringFunc(s, func(m string) string {
+		sm := linkRE.FindStringSubmatch(m)
+		return hold(`<a href="` + html.EscapeString(sm[2]) +
+			`" rel="nofollow noopener" target="_blank">` +
+			html.EscapeString(sm[1]) + `</a>`)
+	})
+	s = html.EscapeString(s)
+	s = urlRE.ReplaceAllStringFunc(s, func(u string) string {
+		return hold(linkify(u))
+	})
+
+	s = strongRE.ReplaceAllString(s, "<strong>$2</strong>")
+	s = emRE.ReplaceAllString(s, "$1<em>$2</em>$3")
+	s = strikeRE.ReplaceAllString(s, "<del>$1</del>")
+
+	for i, l := range links {
+		s = strings.Replace(s, "\x00"+strconv.Itoa(i)+"\x01", l, 1)
+	}
+	return s
+}
+
+// linkify turns bare URLs of an escaped string into links.
+func linkify(s string) string {
+	return urlRE.ReplaceAllStringFunc(s, func(u string) string {
+		return `<a href="` + u + `" rel="nofollow noopener" target="_blank">` +
+			u + `</a>`
+	})
+}
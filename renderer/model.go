@@ -16,6 +16,13 @@ type Context struct {
 	AntiDopamineMode bool
 	UserCSS          string
 	Referrer         string
+	Language         string
+	ProxyCardImages  bool
+	ReduceMotion     bool
+
+	// FilterContext is the context of the filters which apply to the
+	// statuses of the page.
+	FilterContext string
 }
 
 type CommonData struct {
@@ -38,6 +45,7 @@ type ErrorData struct {
 	Err        string
 	Retry      bool
 	SessionErr bool
+	Draft      *model.Draft
 }
 
 type HomePageData struct {
@@ -54,12 +62,15 @@ type RootData struct {
 
 type TimelineData struct {
 	*CommonData
-	Title    string
-	Type     string
-	Instance string
-	Statuses []*mastodon.Status
-	NextLink string
-	PrevLink string
+	Title        string
+	Type         string
+	Instance     string
+	Statuses     []*mastodon.Status
+	NextLink     string
+	PrevLink     string
+	StreamLink   string
+	ContinueLink string
+	Tag          *mastodon.Tag
 }
 
 type ListsData struct {
@@ -67,6 +78,45 @@ type ListsData struct {
 	Lists []*mastodon.List
 }
 
+type DomainBlocksData struct {
+	*CommonData
+	Domains  []string
+	NextLink string
+}
+
+type ReportData struct {
+	*CommonData
+	User     *mastodon.Account
+	Domain   string
+	Statuses []*mastodon.Status
+	StatusID string
+	Rules    []*mastodon.Rule
+}
+
+type ReportedData struct {
+	*CommonData
+	User      *mastodon.Account
+	Domain    string
+	Forwarded bool
+}
+
+type DraftsData struct {
+	*CommonData
+	Drafts []model.Draft
+}
+
+type MuteRulesData struct {
+	*CommonData
+	Rules []model.MuteRule
+}
+
+type ConversationsData struct {
+	*CommonData
+	Conversations []*mastodon.Conversation
+	UnreadCount   int
+	NextLink      string
+}
+
 type ListData struct {
 	*CommonData
 	List           *mastodon.List
@@ -78,10 +128,27 @@ type ListData struct {
 type ThreadData struct {
 	*CommonData
 	Statuses    []*mastodon.Status
+	Tree        []*ThreadNode
 	PostContext model.PostContext
 	ReplyMap    map[string][]mastodon.ReplyInfo
 }
 
+// ThreadNode is a status of a nested thread. Count is the number of statuses
+// below it and Indent tells whether its replies are indented.
+type ThreadNode struct {
+	Status      *mastodon.Status
+	Replies     []*ThreadNode
+	Count       int
+	Indent      bool
+	PostContext *model.PostContext
+}
+
+type PreviewData struct {
+	*CommonData
+	Status      *mastodon.Status
+	PostContext model.PostContext
+}
+
 type QuickReplyData struct {
 	*CommonData
 	Ancestor    *mastodon.Status
@@ -91,20 +158,37 @@ type QuickReplyData struct {
 
 type NotificationData struct {
 	*CommonData
-	Notifications []*mastodon.Notification
+	Notifications []*NotificationGroup
 	UnreadCount   int
 	ReadID        string
 	NextLink      string
+	Type          string
+}
+
+// NotificationGroup is a notification along with the other notifications of
+// the same type on the same status. IDs and Accounts include the ones of the
+// notification itself, Others is the number of accounts not named.
+type NotificationGroup struct {
+	*mastodon.Notification
+	IDs      []string
+	Accounts []mastodon.Account
+	Others   int
+	Unread   bool
 }
 
 type UserData struct {
 	*CommonData
-	User      *mastodon.Account
-	IsCurrent bool
-	Type      string
-	Users     []*mastodon.Account
-	Statuses  []*mastodon.Status
-	NextLink  string
+	User         *mastodon.Account
+	IsCurrent    bool
+	Type         string
+	Tagged       string
+	Domain       string
+	Users        []*mastodon.Account
+	Statuses     []*mastodon.Status
+	Pinned       []*mastodon.Status
+	Tags         []*mastodon.Tag
+	FeaturedTags []*mastodon.FeaturedTag
+	NextLink     string
 }
 
 type UserSearchData struct {
@@ -145,13 +229,50 @@ type SearchData struct {
 	NextLink string
 }
 
+// TrendingTag is a tag with the number of times it has been used and the
+// number of accounts using it recently.
+type TrendingTag struct {
+	*mastodon.Tag
+	Uses     int64
+	Accounts int64
+}
+
+// TrendingLink is a link with the number of accounts sharing it recently.
+type TrendingLink struct {
+	*mastodon.TrendsLink
+	Accounts int64
+}
+
+type ExploreData struct {
+	*CommonData
+	Type        string
+	Tags        []TrendingTag
+	Statuses    []*mastodon.Status
+	Links       []TrendingLink
+	Users       []*mastodon.Account
+	Unsupported bool
+	NextLink    string
+}
+
 type SettingsData struct {
 	*CommonData
-	Settings    *model.Settings
-	PostFormats []model.PostFormat
+	Settings     *model.Settings
+	PostFormats  []model.PostFormat
+	Languages    []model.Language
+	ImageMaxSize int
 }
 
 type FiltersData struct {
 	*CommonData
-	Filters []*mastodon.Filter
+	Filters  []*mastodon.FilterV2
+	Edit     *mastodon.FilterV2
+	Contexts []FilterContext
+	HideOnly bool
+}
+
+// FilterContext is a context which can be selected in the filter editor.
+type FilterContext struct {
+	Name     string
+	Title    string
+	Selected bool
 }
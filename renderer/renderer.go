@@ -3,36 +3,47 @@ package renderer
 import (
 	"html/template"
 	"io"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"bloat/mastodon"
+	"bloat/mediaproxy"
 )
 
 type Page string
 
 const (
-	SigninPage       = "signin.tmpl"
-	ErrorPage        = "error.tmpl"
-	NavPage          = "nav.tmpl"
-	RootPage         = "root.tmpl"
-	TimelinePage     = "timeline.tmpl"
-	ListsPage        = "lists.tmpl"
-	ListPage         = "list.tmpl"
-	ThreadPage       = "thread.tmpl"
-	QuickReplyPage   = "quickreply.tmpl"
-	NotificationPage = "notification.tmpl"
-	UserPage         = "user.tmpl"
-	UserSearchPage   = "usersearch.tmpl"
-	AboutPage        = "about.tmpl"
-	EmojiPage        = "emoji.tmpl"
-	LikedByPage      = "likedby.tmpl"
-	RetweetedByPage  = "retweetedby.tmpl"
-	SearchPage       = "search.tmpl"
-	SettingsPage     = "settings.tmpl"
-	FiltersPage      = "filters.tmpl"
+	SigninPage        = "signin.tmpl"
+	ErrorPage         = "error.tmpl"
+	NavPage           = "nav.tmpl"
+	RootPage          = "root.tmpl"
+	TimelinePage      = "timeline.tmpl"
+	ListsPage         = "lists.tmpl"
+	ListPage          = "list.tmpl"
+	ThreadPage        = "thread.tmpl"
+	QuickReplyPage    = "quickreply.tmpl"
+	NotificationPage  = "notification.tmpl"
+	UserPage          = "user.tmpl"
+	UserSearchPage    = "usersearch.tmpl"
+	AboutPage         = "about.tmpl"
+	EmojiPage         = "emoji.tmpl"
+	LikedByPage       = "likedby.tmpl"
+	RetweetedByPage   = "retweetedby.tmpl"
+	SearchPage        = "search.tmpl"
+	SettingsPage      = "settings.tmpl"
+	FiltersPage       = "filters.tmpl"
+	PreviewPage       = "preview.tmpl"
+	DraftsPage        = "drafts.tmpl"
+	ConversationsPage = "conversations.tmpl"
+	MuteRulesPage     = "muterules.tmpl"
+	ExplorePage       = "explore.tmpl"
+	DomainBlocksPage  = "domainblocks.tmpl"
+	ReportPage        = "report.tmpl"
+	ReportedPage      = "reported.tmpl"
+	StatusFragment    = "status.tmpl"
 )
 
 type TemplateData struct {
@@ -40,25 +51,100 @@ type TemplateData struct {
 	Ctx  *Context
 }
 
-func emojiHTML(e mastodon.Emoji, height string) string {
-	return `<img class="emoji" src="` + e.URL + `" alt=":` + e.ShortCode + `:" title=":` + e.ShortCode + `:" height="` + height + `"/>`
+// emojiURL returns the URL of the static variant of e in reduced motion mode.
+func emojiURL(e mastodon.Emoji, ctx *Context) string {
+	if ctx != nil && ctx.ReduceMotion && len(e.StaticURL) > 0 {
+		return e.StaticURL
+	}
+	return e.URL
+}
+
+// avatarURL returns the URL of the static avatar of a in reduced motion mode.
+func avatarURL(a mastodon.Account, ctx *Context) string {
+	if ctx != nil && ctx.ReduceMotion && len(a.AvatarStatic) > 0 {
+		return a.AvatarStatic
+	}
+	return a.Avatar
+}
+
+func isGIF(u string) bool {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(pu.Path), ".gif")
+}
+
+// inFilterContext reports whether the filter of r applies to the filter
+// context of the page.
+func inFilterContext(r mastodon.FilterResult, ctx *Context) bool {
+	return ctx == nil || len(ctx.FilterContext) < 1 ||
+		containsString(r.Filter.Context, ctx.FilterContext)
+}
+
+// filterTitles returns the titles of the filters with the warn action which
+// matched s, or the status retweeted by s, in the filter context of the
+// page, along with the local mute rule which collapses it.
+func filterTitles(s *mastodon.Status, ctx *Context) string {
+	var titles []string
+	for ; s != nil; s = s.Reblog {
+		if len(s.CollapsedBy) > 0 && !containsString(titles, s.CollapsedBy) {
+			titles = append(titles, s.CollapsedBy)
+		}
+		for _, r := range s.Filtered {
+			if r.Filter.FilterAction == "hide" || !inFilterContext(r, ctx) {
+				continue
+			}
+			if !containsString(titles, r.Filter.Title) {
+				titles = append(titles, r.Filter.Title)
+			}
+		}
+	}
+	return strings.Join(titles, ", ")
+}
+
+// filterHidden reports whether a filter with the hide action matched s, or
+// the status retweeted by s, in the filter context of the page.
+func filterHidden(s *mastodon.Status, ctx *Context) bool {
+	for ; s != nil; s = s.Reblog {
+		for _, r := range s.Filtered {
+			if r.Filter.FilterAction == "hide" && inFilterContext(r, ctx) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(a []string, s string) bool {
+	for i := range a {
+		if a[i] == s {
+			return true
+		}
+	}
+	return false
+}
+
+func emojiHTML(e mastodon.Emoji, height string, ctx *Context, mp *mediaproxy.Proxy) string {
+	src := template.HTMLEscapeString(mp.URL(emojiURL(e, ctx)))
+	return `<img class="emoji" src="` + src + `" alt=":` + e.ShortCode + `:" title=":` + e.ShortCode + `:" height="` + height + `"/>`
 }
 
-func emojiFilter(content string, emojis []mastodon.Emoji) string {
+func emojiFilter(content string, emojis []mastodon.Emoji, ctx *Context, mp *mediaproxy.Proxy) string {
 	var replacements []string
 	for _, e := range emojis {
-		replacements = append(replacements, ":"+e.ShortCode+":", emojiHTML(e, "24"))
+		replacements = append(replacements, ":"+e.ShortCode+":", emojiHTML(e, "24", ctx, mp))
 	}
 	return strings.NewReplacer(replacements...).Replace(content)
 }
 
 var quoteRE = regexp.MustCompile("(?mU)(^|> *|\n)(&gt;.*)(<br|$)")
 
-func statusContentFilter(content string, emojis []mastodon.Emoji, mentions []mastodon.Mention) string {
+func statusContentFilter(content string, emojis []mastodon.Emoji, mentions []mastodon.Mention, ctx *Context, mp *mediaproxy.Proxy) string {
 	content = quoteRE.ReplaceAllString(content, `$1<span class="quote">$2</span>$3`)
 	var replacements []string
 	for _, e := range emojis {
-		replacements = append(replacements, ":"+e.ShortCode+":", emojiHTML(e, "32"))
+		replacements = append(replacements, ":"+e.ShortCode+":", emojiHTML(e, "32", ctx, mp))
 	}
 	for _, m := range mentions {
 		replacements = append(replacements, `"`+m.URL+`"`, `"/user/`+m.ID+`" title="@`+m.Acct+`"`)
@@ -138,11 +224,30 @@ type renderer struct {
 	template *template.Template
 }
 
-func NewRenderer(templateGlobPattern string) (r *renderer, err error) {
+// NewRenderer creates a renderer which rewrites the URLs of remote media to
+// go through the media proxy, if mp is not nil.
+func NewRenderer(templateGlobPattern string, mp *mediaproxy.Proxy) (r *renderer, err error) {
 	t := template.New("default")
 	t, err = t.Funcs(template.FuncMap{
-		"EmojiFilter":             emojiFilter,
-		"StatusContentFilter":     statusContentFilter,
+		"EmojiFilter": func(content string, emojis []mastodon.Emoji,
+			ctx *Context) string {
+			return emojiFilter(content, emojis, ctx, mp)
+		},
+		"StatusContentFilter": func(content string, emojis []mastodon.Emoji,
+			mentions []mastodon.Mention, ctx *Context) string {
+			return statusContentFilter(content, emojis, mentions, ctx, mp)
+		},
+		"EmojiURL": func(e mastodon.Emoji, ctx *Context) string {
+			return mp.URL(emojiURL(e, ctx))
+		},
+		"AvatarURL": func(a mastodon.Account, ctx *Context) string {
+			return mp.URL(avatarURL(a, ctx))
+		},
+		"MediaURL":                mp.URL,
+		"CardImageURL":            mp.CardImageURL,
+		"IsGIF":                   isGIF,
+		"FilterTitles":            filterTitles,
+		"FilterHidden":            filterHidden,
 		"DisplayInteractionCount": displayInteractionCount,
 		"TimeSince":               timeSince,
 		"TimeUntil":               timeUntil,
@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	"bloat/kv"
+	"bloat/model"
+)
+
+const maxDrafts = 50
+
+var ErrNoSuchDraft = errors.New("no such draft")
+
+// DraftRepo stores the drafts of every account as a single list.
+type DraftRepo struct {
+	db *kv.Database
+	m  sync.Mutex
+}
+
+func NewDraftRepo(db *kv.Database) *DraftRepo {
+	return &DraftRepo{
+		db: db,
+	}
+}
+
+func draftKey(owner string) string {
+	return "drafts_" + owner
+}
+
+func (repo *DraftRepo) list(owner string) (drafts []model.Draft, err error) {
+	data, err := repo.db.Get(draftKey(owner))
+	if err == kv.ErrNoSuchKey {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &drafts)
+	return
+}
+
+func (repo *DraftRepo) save(owner string, drafts []model.Draft) error {
+	if len(drafts) < 1 {
+		return repo.db.Remove(draftKey(owner))
+	}
+	data, err := json.Marshal(drafts)
+	if err != nil {
+		return err
+	}
+	return repo.db.Set(draftKey(owner), data)
+}
+
+// List returns the drafts of owner, most recently updated first.
+func (repo *DraftRepo) List(owner string) ([]model.Draft, error) {
+	repo.m.Lock()
+	defer repo.m.Unlock()
+	drafts, err := repo.list(owner)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(drafts, func(i, j int) bool {
+		return drafts[i].UpdatedAt.After(drafts[j].UpdatedAt)
+	})
+	return drafts, nil
+}
+
+func (repo *DraftRepo) Get(owner string, id string) (d model.Draft, err error) {
+	repo.m.Lock()
+	defer repo.m.Unlock()
+	drafts, err := repo.list(owner)
+	if err != nil {
+		return
+	}
+	for i := range drafts {
+		if drafts[i].ID == id {
+			return drafts[i], nil
+		}
+	}
+	return d, ErrNoSuchDraft
+}
+
+// Add adds a draft or replaces the draft with the same ID. The oldest draft
+// is dropped once an account has too many of them.
+func (repo *DraftRepo) Add(owner string, d model.Draft) error {
+	repo.m.Lock()
+	defer repo.m.Unlock()
+	drafts, err := repo.list(owner)
+	if err != nil {
+		return err
+	}
+	var found bool
+	for i := range drafts {
+		if drafts[i].ID == d.ID {
+			drafts[i] = d
+			found = true
+			break
+		}
+	}
+	if !found {
+		drafts = append(drafts, d)
+	}
+	if len(drafts) > maxDrafts {
+		oldest := 0
+		for i := range drafts {
+			if drafts[i].UpdatedAt.Before(drafts[oldest].UpdatedAt) {
+				oldest = i
+			}
+		}
+		drafts = append(drafts[:oldest], drafts[oldest+1:]...)
+	}
+	return repo.save(owner, drafts)
+}
+
+func (repo *DraftRepo) Remove(owner string, id string) error {
+	repo.m.Lock()
+	defer repo.m.Unlock()
+	drafts, err := repo.list(owner)
+	if err != nil {
+		return err
+	}
+	for i := range drafts {
+		if drafts[i].ID == id {
+			drafts = append(drafts[:i], drafts[i+1:]...)
+			return repo.save(owner, drafts)
+		}
+	}
+	return nil
+}
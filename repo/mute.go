@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"bloat/kv"
+	"bloat/model"
+)
+
+const maxMuteRules = 100
+
+var ErrTooManyMuteRules = errors.New("too many mute rules")
+
+// MuteRuleRepo stores the mute rules of every account as a single list.
+type MuteRuleRepo struct {
+	db *kv.Database
+	m  sync.Mutex
+}
+
+func NewMuteRuleRepo(db *kv.Database) *MuteRuleRepo {
+	return &MuteRuleRepo{
+		db: db,
+	}
+}
+
+func muteRuleKey(owner string) string {
+	return "muterules_" + owner
+}
+
+// List returns the mute rules of owner in the order they were added.
+func (repo *MuteRuleRepo) List(owner string) (rules []model.MuteRule, err error) {
+	repo.m.Lock()
+	defer repo.m.Unlock()
+	return repo.list(owner)
+}
+
+func (repo *MuteRuleRepo) list(owner string) (rules []model.MuteRule, err error) {
+	data, err := repo.db.Get(muteRuleKey(owner))
+	if err == kv.ErrNoSuchKey {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &rules)
+	return
+}
+
+func (repo *MuteRuleRepo) save(owner string, rules []model.MuteRule) error {
+	if len(rules) < 1 {
+		return repo.db.Remove(muteRuleKey(owner))
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	return repo.db.Set(muteRuleKey(owner), data)
+}
+
+func (repo *MuteRuleRepo) Add(owner string, r model.MuteRule) error {
+	repo.m.Lock()
+	defer repo.m.Unlock()
+	rules, err := repo.list(owner)
+	if err != nil {
+		return err
+	}
+	if len(rules) >= maxMuteRules {
+		return ErrTooManyMuteRules
+	}
+	return repo.save(owner, append(rules, r))
+}
+
+func (repo *MuteRuleRepo) Remove(owner string, id string) error {
+	repo.m.Lock()
+	defer repo.m.Unlock()
+	rules, err := repo.list(owner)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		if rules[i].ID == id {
+			rules = append(rules[:i], rules[i+1:]...)
+			return repo.save(owner, rules)
+		}
+	}
+	return nil
+}
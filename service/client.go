@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -13,14 +14,40 @@ import (
 	"bloat/renderer"
 )
 
+// responseWriter remembers whether the response has been started, as the
+// status code can't be changed afterwards.
+type responseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.started = true
+		f.Flush()
+	}
+}
+
 type client struct {
 	*mastodon.Client
-	w    http.ResponseWriter
+	w    *responseWriter
 	r    *http.Request
 	s    *model.Session
 	csrf string
 	ctx  context.Context
 	rctx *renderer.Context
+
+	logger *log.Logger
 }
 
 func (c *client) setSession(sess *model.Session) error {
@@ -63,6 +90,12 @@ func (c *client) writeJson(data interface{}) error {
 	})
 }
 
+// logError logs the error of a request which doesn't fail the page, such
+// as a request for optional data.
+func (c *client) logError(err error) {
+	c.logger.Printf("path=%s, ignored err=%v\n", c.r.URL.Path, err)
+}
+
 func (c *client) redirect(url string) {
 	c.w.Header().Add("Location", url)
 	c.w.WriteHeader(http.StatusFound)
@@ -87,6 +120,9 @@ func (c *client) authenticate(t int) (err error) {
 			UserID:           c.s.UserID,
 			AntiDopamineMode: c.s.Settings.AntiDopamineMode,
 			UserCSS:          c.s.Settings.CSS,
+			Language:         c.s.DefaultLanguage(),
+			ProxyCardImages:  c.s.Settings.ProxyCardImages,
+			ReduceMotion:     c.s.Settings.ReduceMotion,
 			Referrer:         ref,
 		}
 	}()
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"bloat/mastodon"
+	"bloat/model"
+	"bloat/renderer"
+	"bloat/util"
+)
+
+var (
+	lineBreakRE = regexp.MustCompile(`(?i)<br\s*/?>|</p>`)
+	htmlTagRE   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// muteNotificationTypes are the types of notifications about statuses of
+// other accounts, which are the ones mute rules apply to.
+var muteNotificationTypes = []string{"mention", "status", "update"}
+
+type muteRule struct {
+	model.MuteRule
+	re *regexp.Regexp
+}
+
+// muteRules returns the mute rules of the user. Rules with invalid patterns,
+// which can't be added anyway, are skipped.
+func (s *service) muteRules(c *client) (rules []*muteRule, err error) {
+	owner, err := s.accountOwner(c)
+	if err != nil {
+		return
+	}
+	list, err := s.muteRuleRepo.List(owner)
+	if err != nil {
+		return
+	}
+	for _, r := range list {
+		mr := &muteRule{MuteRule: r}
+		if len(r.Pattern) > 0 {
+			mr.re, err = regexp.Compile(r.Pattern)
+			if err != nil {
+				err = nil
+				continue
+			}
+		}
+		if !r.IsEmpty() {
+			rules = append(rules, mr)
+		}
+	}
+	return
+}
+
+// normalizeAcct returns acct in the form user@domain, where domain is the
+// instance of the session for local accounts.
+func normalizeAcct(acct string, instance string) string {
+	acct = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(acct), "@"))
+	if !strings.Contains(acct, "@") {
+		acct += "@" + strings.ToLower(instance)
+	}
+	return acct
+}
+
+// statusText returns the spoiler text and the content of s as plain text.
+func statusText(s *mastodon.Status) string {
+	content := lineBreakRE.ReplaceAllString(s.Content, "\n")
+	content = htmlTagRE.ReplaceAllString(content, "")
+	return s.SpoilerText + "\n" + html.UnescapeString(content)
+}
+
+func (r *muteRule) match(s *mastodon.Status, instance string) bool {
+	st := s
+	if s.Reblog != nil {
+		st = s.Reblog
+	}
+	if len(r.BoostsFrom) > 0 && (s.Reblog == nil ||
+		normalizeAcct(s.Account.Acct, instance) !=
+			normalizeAcct(r.BoostsFrom, instance)) {
+		return false
+	}
+	acct := normalizeAcct(st.Account.Acct, instance)
+	if len(r.Account) > 0 && acct != normalizeAcct(r.Account, instance) {
+		return false
+	}
+	if len(r.Domain) > 0 {
+		domain := strings.ToLower(strings.TrimSpace(r.Domain))
+		d := acct[strings.LastIndex(acct, "@")+1:]
+		if d != domain && !strings.HasSuffix(d, "."+domain) {
+			return false
+		}
+	}
+	if r.NoMedia && len(st.MediaAttachments) > 0 {
+		return false
+	}
+	if r.re != nil && !r.re.MatchString(statusText(st)) {
+		return false
+	}
+	return true
+}
+
+// matchMuteRules returns the action for s. Hiding takes precedence over
+// collapsing, which is described by the first rule asking for it.
+func matchMuteRules(rules []*muteRule, s *mastodon.Status,
+	instance string) (action string, desc string) {
+	for _, r := range rules {
+		if !r.match(s, instance) {
+			continue
+		}
+		if r.Action == "hide" {
+			return r.Action, r.String()
+		}
+		if len(action) < 1 {
+			action, desc = r.Action, r.String()
+		}
+	}
+	return
+}
+
+// applyMuteRules removes the statuses which are hidden by the rules and
+// marks the collapsed ones. The statuses with the ids in keep are collapsed
+// instead of being hidden.
+func applyMuteRules(rules []*muteRule, statuses []*mastodon.Status,
+	instance string, keep map[string]bool) []*mastodon.Status {
+	if len(rules) < 1 {
+		return statuses
+	}
+	res := make([]*mastodon.Status, 0, len(statuses))
+	for _, s := range statuses {
+		action, desc := matchMuteRules(rules, s, instance)
+		if action == "hide" && !keep[s.ID] {
+			continue
+		}
+		if len(action) > 0 {
+			s.CollapsedBy = desc
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// muteNotifications is like applyMuteRules for the statuses of
+// notifications.
+func muteNotifications(rules []*muteRule,
+	notifications []*mastodon.Notification,
+	instance string) []*mastodon.Notification {
+	if len(rules) < 1 {
+		return notifications
+	}
+	res := make([]*mastodon.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		if n.Status != nil && containsString(muteNotificationTypes, n.Type) {
+			action, desc := matchMuteRules(rules, n.Status, instance)
+			if action == "hide" {
+				continue
+			}
+			if len(action) > 0 {
+				n.Status.CollapsedBy = desc
+			}
+		}
+		res = append(res, n)
+	}
+	return res
+}
+
+func (s *service) MuteRulesPage(c *client) (err error) {
+	owner, err := s.accountOwner(c)
+	if err != nil {
+		return
+	}
+	rules, err := s.muteRuleRepo.List(owner)
+	if err != nil {
+		return
+	}
+	cdata := s.cdata(c, "mute rules", 0, 0, "")
+	data := &renderer.MuteRulesData{
+		CommonData: cdata,
+		Rules:      rules,
+	}
+	return s.renderer.Render(c.rctx, c.w, renderer.MuteRulesPage, data)
+}
+
+func (s *service) AddMuteRule(c *client, r model.MuteRule) (err error) {
+	r.Pattern = strings.TrimSpace(r.Pattern)
+	r.Account = strings.TrimPrefix(strings.TrimSpace(r.Account), "@")
+	r.Domain = strings.ToLower(strings.TrimSpace(r.Domain))
+	r.BoostsFrom = strings.TrimPrefix(strings.TrimSpace(r.BoostsFrom), "@")
+	if r.IsEmpty() || (r.Action != "hide" && r.Action != "collapse") {
+		return errInvalidArgument
+	}
+	if len(r.Pattern) > 0 {
+		_, err = regexp.Compile(r.Pattern)
+		if err != nil {
+			return
+		}
+	}
+	owner, err := s.accountOwner(c)
+	if err != nil {
+		return
+	}
+	r.ID, err = util.NewMuteRuleID()
+	if err != nil {
+		return
+	}
+	r.CreatedAt = time.Now()
+	return s.muteRuleRepo.Add(owner, r)
+}
+
+func (s *service) RemoveMuteRule(c *client, id string) (err error) {
+	owner, err := s.accountOwner(c)
+	if err != nil {
+		return
+	}
+	return s.muteRuleRepo.Remove(owner, id)
+}
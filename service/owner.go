@@ -0,0 +1,78 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+)
+
+const ownerCacheSize = 4096
+
+type ownerCacheEntry struct {
+	key string
+	id  string
+}
+
+// ownerCache remembers the accounts which own access tokens. The user id in
+// the session can't be trusted, but looking up the account on every request
+// would be slow. The least recently used entries are dropped once the cache
+// is full.
+type ownerCache struct {
+	m       sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+func newOwnerCache() *ownerCache {
+	return &ownerCache{
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (oc *ownerCache) get(key string) (id string, ok bool) {
+	oc.m.Lock()
+	defer oc.m.Unlock()
+	el, ok := oc.entries[key]
+	if !ok {
+		return "", false
+	}
+	oc.lru.MoveToFront(el)
+	return el.Value.(*ownerCacheEntry).id, true
+}
+
+func (oc *ownerCache) put(key string, id string) {
+	oc.m.Lock()
+	defer oc.m.Unlock()
+	if el, ok := oc.entries[key]; ok {
+		el.Value.(*ownerCacheEntry).id = id
+		oc.lru.MoveToFront(el)
+		return
+	}
+	oc.entries[key] = oc.lru.PushFront(&ownerCacheEntry{key, id})
+	if oc.lru.Len() > ownerCacheSize {
+		el := oc.lru.Back()
+		oc.lru.Remove(el)
+		delete(oc.entries, el.Value.(*ownerCacheEntry).key)
+	}
+}
+
+// ownerKey returns the key under which the data of an account, such as its
+// idempotency keys and drafts, is stored.
+func ownerKey(c *client, userID string) string {
+	return userID + "@" + c.s.Instance
+}
+
+// accountOwner returns the key under which the data of the account which
+// owns the access token of the session is stored.
+func (s *service) accountOwner(c *client) (string, error) {
+	key := c.s.Instance + " " + c.s.AccessToken
+	if id, ok := s.owners.get(key); ok {
+		return ownerKey(c, id), nil
+	}
+	u, err := c.GetAccountCurrentUser(c.ctx)
+	if err != nil {
+		return "", err
+	}
+	s.owners.put(key, u.ID)
+	return ownerKey(c, u.ID), nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	postCacheTTL  = time.Hour
+	postCacheSize = 4096
+)
+
+type postCacheEntry struct {
+	id   string
+	busy chan struct{}
+	t    time.Time
+}
+
+// postCache remembers the statuses created for recent idempotency keys, so
+// that a form which is submitted again does not create another status.
+type postCache struct {
+	m       sync.Mutex
+	entries map[string]*postCacheEntry
+}
+
+func newPostCache() *postCache {
+	return &postCache{
+		entries: make(map[string]*postCacheEntry),
+	}
+}
+
+// claim returns the id of the status created for key. If there is none, the
+// key is claimed and an empty id is returned; the caller must then call
+// release once it has tried to post. Concurrent claims for the same key wait
+// until the key is released.
+func (pc *postCache) claim(ctx context.Context, key string) (id string, err error) {
+	for {
+		pc.m.Lock()
+		e, ok := pc.entries[key]
+		if !ok {
+			pc.entries[key] = &postCacheEntry{
+				busy: make(chan struct{}),
+				t:    time.Now(),
+			}
+			pc.m.Unlock()
+			return "", nil
+		}
+		busy := e.busy
+		id = e.id
+		pc.m.Unlock()
+
+		if busy == nil {
+			return id, nil
+		}
+		select {
+		case <-busy:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// release records the id of the status created for a claimed key. An empty
+// id means that posting failed, in which case the key can be claimed again.
+func (pc *postCache) release(key string, id string) {
+	pc.m.Lock()
+	defer pc.m.Unlock()
+	e, ok := pc.entries[key]
+	if !ok {
+		return
+	}
+	close(e.busy)
+	e.busy = nil
+	if len(id) < 1 {
+		delete(pc.entries, key)
+		return
+	}
+	e.id = id
+	e.t = time.Now()
+	pc.prune()
+}
+
+func (pc *postCache) prune() {
+	if len(pc.entries) < postCacheSize {
+		return
+	}
+	var oldestKey string
+	var oldest time.Time
+	for k, e := range pc.entries {
+		if e.busy != nil {
+			continue
+		}
+		if time.Since(e.t) > postCacheTTL {
+			delete(pc.entries, k)
+		} else if len(oldestKey) < 1 || e.t.Before(oldest) {
+			oldestKey, oldest = k, e.t
+		}
+	}
+	if len(pc.entries) >= postCacheSize && len(oldestKey) > 0 {
+		delete(pc.entries, oldestKey)
+	}
+}
@@ -1,15 +1,22 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"mime/multipart"
 	"net/url"
 	"strings"
+	"time"
 
 	"bloat/mastodon"
+	"bloat/media"
+	"bloat/mediaproxy"
 	"bloat/model"
 	"bloat/renderer"
+	"bloat/repo"
 	"bloat/util"
 )
 
@@ -17,29 +24,94 @@ var (
 	errInvalidArgument  = errors.New("invalid argument")
 	errInvalidSession   = errors.New("invalid session")
 	errInvalidCSRFToken = errors.New("invalid csrf token")
+	errNoPreview        = errors.New("preview is not supported for this format")
+	errMediaTimeout     = errors.New("media processing timed out")
 )
 
+const (
+	mediaPollInterval      = time.Second
+	mediaProcessingTimeout = 2 * time.Minute
+	maxThreadDepth         = 6
+
+	// notificationExtraFetches limits the number of additional requests
+	// made to fill a page of grouped notifications.
+	notificationExtraFetches = 2
+
+	// maxNotificationGroupKeys limits the number of groups which are
+	// continued on the next page of notifications.
+	maxNotificationGroupKeys = 40
+)
+
+// draftError is returned when posting fails and the post has been saved as
+// a draft instead.
+type draftError struct {
+	err   error
+	draft *model.Draft
+}
+
+func (e *draftError) Error() string {
+	return e.err.Error()
+}
+
+// mediaError names the attachment which could not be uploaded.
+type mediaError struct {
+	name string
+	err  error
+}
+
+func (e *mediaError) Error() string {
+	return e.name + ": " + e.err.Error()
+}
+
+func (e *mediaError) Unwrap() error {
+	return e.err
+}
+
+func isAuthError(err error) bool {
+	var me mastodon.Error
+	return errors.As(err, &me) && me.IsAuthError()
+}
+
+func isNotFound(err error) bool {
+	var me mastodon.Error
+	return errors.As(err, &me) && me.IsNotFound()
+}
+
 type service struct {
-	cname       string
-	cscope      string
-	cwebsite    string
-	css         string
-	instance    string
-	postFormats []model.PostFormat
-	renderer    renderer.Renderer
+	cname        string
+	cscope       string
+	cwebsite     string
+	css          string
+	instance     string
+	postFormats  []model.PostFormat
+	imageMaxSize int
+	renderer     renderer.Renderer
+	postCache    *postCache
+	owners       *ownerCache
+	draftRepo    *repo.DraftRepo
+	muteRuleRepo *repo.MuteRuleRepo
+	mediaProxy   *mediaproxy.Proxy
 }
 
 func NewService(cname string, cscope string, cwebsite string,
 	css string, instance string, postFormats []model.PostFormat,
-	renderer renderer.Renderer) *service {
+	imageMaxSize int, renderer renderer.Renderer,
+	draftRepo *repo.DraftRepo, muteRuleRepo *repo.MuteRuleRepo,
+	mediaProxy *mediaproxy.Proxy) *service {
 	return &service{
-		cname:       cname,
-		cscope:      cscope,
-		cwebsite:    cwebsite,
-		css:         css,
-		instance:    instance,
-		postFormats: postFormats,
-		renderer:    renderer,
+		cname:        cname,
+		cscope:       cscope,
+		cwebsite:     cwebsite,
+		css:          css,
+		instance:     instance,
+		postFormats:  postFormats,
+		imageMaxSize: imageMaxSize,
+		renderer:     renderer,
+		postCache:    newPostCache(),
+		owners:       newOwnerCache(),
+		draftRepo:    draftRepo,
+		muteRuleRepo: muteRuleRepo,
+		mediaProxy:   mediaProxy,
 	}
 }
 
@@ -61,10 +133,14 @@ func (s *service) cdata(c *client, title string, count int, rinterval int,
 func (s *service) ErrorPage(c *client, err error, retry bool) error {
 	var errStr string
 	var sessionErr bool
+	var draft *model.Draft
+	if de, ok := err.(*draftError); ok {
+		draft = de.draft
+		err = de.err
+	}
 	if err != nil {
 		errStr = err.Error()
-		if me, ok := err.(mastodon.Error); ok && me.IsAuthError() ||
-			err == errInvalidSession || err == errInvalidCSRFToken {
+		if isAuthError(err) || err == errInvalidSession || err == errInvalidCSRFToken {
 			sessionErr = true
 		}
 	}
@@ -74,6 +150,7 @@ func (s *service) ErrorPage(c *client, err error, retry bool) error {
 		Err:        errStr,
 		Retry:      retry,
 		SessionErr: sessionErr,
+		Draft:      draft,
 	}
 	return s.renderer.Render(c.rctx, c.w, renderer.ErrorPage, data)
 }
@@ -93,15 +170,46 @@ func (s *service) RootPage(c *client) (err error) {
 	return s.renderer.Render(c.rctx, c.w, renderer.RootPage, data)
 }
 
-func (s *service) NavPage(c *client) (err error) {
+// storeLanguage stores the language of the account u in sessions which were
+// created before it was stored in them, so that it doesn't have to be looked
+// up on every page.
+func storeLanguage(c *client, u *mastodon.Account) error {
+	if len(c.s.Language) > 0 || u.Source == nil || u.Source.Language == nil ||
+		len(*u.Source.Language) < 1 {
+		return nil
+	}
+	c.s.Language = *u.Source.Language
+	c.rctx.Language = c.s.DefaultLanguage()
+	return c.setSession(c.s)
+}
+
+func (s *service) NavPage(c *client, draftID string) (err error) {
 	u, err := c.GetAccountCurrentUser(c.ctx)
 	if err != nil {
 		return
 	}
+	err = storeLanguage(c, u)
+	if err != nil {
+		return
+	}
+	key, err := util.NewIdempotencyKey()
+	if err != nil {
+		return
+	}
 	pctx := model.PostContext{
 		DefaultVisibility: c.s.Settings.DefaultVisibility,
 		DefaultFormat:     c.s.Settings.DefaultFormat,
+		DefaultLanguage:   c.s.DefaultLanguage(),
+		IdempotencyKey:    key,
 		Formats:           s.postFormats,
+		Languages:         model.Languages,
+	}
+	if len(draftID) > 0 {
+		d, err := s.draftRepo.Get(ownerKey(c, u.ID), draftID)
+		if err != nil {
+			return err
+		}
+		applyDraft(&pctx, &d)
 	}
 	cdata := s.cdata(c, "nav", 0, 0, "main")
 	data := &renderer.NavData{
@@ -112,11 +220,12 @@ func (s *service) NavPage(c *client) (err error) {
 	return s.renderer.Render(c.rctx, c.w, renderer.NavPage, data)
 }
 
-func (s *service) TimelinePage(c *client, tType, instance, listId, maxID,
-	minID string) (err error) {
+func (s *service) TimelinePage(c *client, tType, instance, listId, tag,
+	maxID, minID string) (err error) {
 
-	var nextLink, prevLink, title string
+	var nextLink, prevLink, streamLink, title string
 	var statuses []*mastodon.Status
+	var tagInfo *mastodon.Tag
 	var pg = mastodon.Pagination{
 		MaxID: maxID,
 		MinID: minID,
@@ -168,6 +277,24 @@ func (s *service) TimelinePage(c *client, tType, instance, listId, maxID,
 			return err
 		}
 		title = "List Timeline - " + list.Title
+	case "tag":
+		tag = strings.TrimPrefix(tag, "#")
+		if !isTagName(tag) {
+			return errInvalidArgument
+		}
+		statuses, err = c.GetTimelineHashtag(c.ctx, tag, false, &pg)
+		if err != nil {
+			return err
+		}
+		title = "Tag Timeline - #" + tag
+		// Only newer servers let you follow tags.
+		tagInfo, err = c.GetTag(c.ctx, tag)
+		if isNotFound(err) {
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
 	}
 
 	for i := range statuses {
@@ -175,6 +302,15 @@ func (s *service) TimelinePage(c *client, tType, instance, listId, maxID,
 			statuses[i].Reblog.RetweetedByID = statuses[i].ID
 		}
 	}
+	c.rctx.FilterContext = timelineFilterContext(tType)
+
+	// The links to other pages are based on the unfiltered statuses.
+	rules, err := s.muteRules(c)
+	if err != nil {
+		return
+	}
+	visible := applyMuteRules(rules, statuses, c.s.Instance, nil)
+	annotateStatuses(c, visible)
 
 	if (len(maxID) > 0 || len(minID) > 0) && len(statuses) > 0 {
 		v := make(url.Values)
@@ -185,6 +321,9 @@ func (s *service) TimelinePage(c *client, tType, instance, listId, maxID,
 		if len(listId) > 0 {
 			v.Set("list", listId)
 		}
+		if len(tag) > 0 {
+			v.Set("tag", tag)
+		}
 		prevLink = "/timeline/" + tType + "?" + v.Encode()
 	}
 
@@ -197,22 +336,102 @@ func (s *service) TimelinePage(c *client, tType, instance, listId, maxID,
 		if len(listId) > 0 {
 			v.Set("list", listId)
 		}
+		if len(tag) > 0 {
+			v.Set("tag", tag)
+		}
 		nextLink = "/timeline/" + tType + "?" + v.Encode()
 	}
 
+	// New statuses can only be shown on the first page.
+	if len(maxID) < 1 && len(minID) < 1 {
+		switch tType {
+		case "home", "local", "twkn":
+			streamLink = "/stream/timeline/" + tType
+		case "list":
+			streamLink = "/stream/timeline/list?list=" + url.QueryEscape(listId)
+		case "tag":
+			streamLink = "/stream/timeline/tag?tag=" + url.QueryEscape(tag)
+		}
+	}
+
+	var continueLink string
+	if tType == "home" {
+		continueLink = s.readHome(c, statuses, len(maxID) < 1 &&
+			len(minID) < 1)
+	}
+
 	cdata := s.cdata(c, tType+" timeline ", 0, 0, "")
 	data := &renderer.TimelineData{
-		Title:      title,
-		Type:       tType,
-		Instance:   instance,
-		Statuses:   statuses,
-		NextLink:   nextLink,
-		PrevLink:   prevLink,
-		CommonData: cdata,
+		Title:        title,
+		Type:         tType,
+		Instance:     instance,
+		Statuses:     visible,
+		NextLink:     nextLink,
+		PrevLink:     prevLink,
+		StreamLink:   streamLink,
+		ContinueLink: continueLink,
+		Tag:          tagInfo,
+		CommonData:   cdata,
 	}
 	return s.renderer.Render(c.rctx, c.w, renderer.TimelinePage, data)
 }
 
+// newerID reports whether the id a is newer than b. Ids are compared as
+// numbers of any length, which also works for the flake ids of Pleroma.
+func newerID(a string, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// lastReadID returns the id in the marker of timeline, which is empty if
+// there is no marker yet. ok is false if the server doesn't support markers.
+func lastReadID(c *client, timeline string) (id string, ok bool, err error) {
+	markers, err := c.GetMarkers(c.ctx, timeline)
+	if isNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if m := markers[timeline]; m != nil {
+		id = m.LastReadID
+	}
+	return id, true, nil
+}
+
+// readHome advances the home marker to the newest of the statuses. If the
+// first page doesn't reach the marker, the statuses in between haven't been
+// read, so the marker is kept and a link to continue from it is returned.
+// Markers are only an aid, so errors don't fail the page.
+func (s *service) readHome(c *client, statuses []*mastodon.Status,
+	isFirstPage bool) (continueLink string) {
+
+	if len(statuses) < 1 {
+		return
+	}
+	lastRead, ok, err := lastReadID(c, "home")
+	if err != nil {
+		c.logError(err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if isFirstPage && len(lastRead) > 0 && len(statuses) == 20 &&
+		newerID(statuses[len(statuses)-1].ID, lastRead) {
+		return "/timeline/home?min_id=" + url.QueryEscape(lastRead)
+	}
+	if newerID(statuses[0].ID, lastRead) {
+		err = c.SetMarker(c.ctx, "home", statuses[0].ID)
+		if err != nil {
+			c.logError(err)
+		}
+	}
+	return
+}
+
 func addToReplyMap(m map[string][]mastodon.ReplyInfo, key interface{},
 	val string, number int) {
 	if key == nil {
@@ -226,7 +445,67 @@ func addToReplyMap(m map[string][]mastodon.ReplyInfo, key interface{},
 	if !ok {
 		m[keyStr] = []mastodon.ReplyInfo{}
 	}
-	m[keyStr] = append(m[keyStr], mastodon.ReplyInfo{val, number})
+	m[keyStr] = append(m[keyStr], mastodon.ReplyInfo{ID: val, Number: number})
+}
+
+// buildThreadTree arranges the statuses of a thread by their replies. A
+// status with a single reply is continued at the same level, only branches
+// are indented, up to maxThreadDepth levels. The post form is attached to the
+// status being replied to.
+func buildThreadTree(statuses []*mastodon.Status,
+	pctx *model.PostContext) []*renderer.ThreadNode {
+	// Statuses are in thread order, so looking up parents among the
+	// preceding statuses is enough and rules out cycles.
+	nodes := make(map[string]*renderer.ThreadNode, len(statuses))
+	var roots []*renderer.ThreadNode
+	for _, st := range statuses {
+		n := &renderer.ThreadNode{Status: st}
+		if pctx.ReplyContext != nil &&
+			pctx.ReplyContext.InReplyToID == st.ID {
+			n.PostContext = pctx
+		}
+		id, _ := st.InReplyToID.(string)
+		if p, ok := nodes[id]; ok {
+			p.Replies = append(p.Replies, n)
+		} else {
+			roots = append(roots, n)
+		}
+		nodes[st.ID] = n
+	}
+	var walk func(ns []*renderer.ThreadNode, depth int) int
+	walk = func(ns []*renderer.ThreadNode, depth int) (count int) {
+		for _, n := range ns {
+			d := depth
+			if len(n.Replies) > 1 && d < maxThreadDepth {
+				n.Indent = true
+				d++
+			}
+			n.Count = walk(n.Replies, d)
+			count += n.Count + 1
+		}
+		return count
+	}
+	walk(roots, 0)
+	return roots
+}
+
+// isTagName reports whether name can be used as a path segment of the API,
+// which tag names containing slashes or consisting of dots can't.
+func isTagName(name string) bool {
+	return len(name) > 0 && !strings.Contains(name, "/") &&
+		name != "." && name != ".."
+}
+
+// timelineFilterContext returns the context of the filters which apply to a
+// timeline. All filters apply to timelines without a context of their own.
+func timelineFilterContext(tType string) string {
+	switch tType {
+	case "home", "list":
+		return "home"
+	case "local", "remote", "twkn", "tag":
+		return "public"
+	}
+	return ""
 }
 
 func (s *service) ListsPage(c *client) (err error) {
@@ -293,8 +572,18 @@ func (s *service) ListRemoveUser(c *client, id string, uid string) (err error) {
 	return c.RemoveFromList(c.ctx, id, uid)
 }
 
-func (s *service) ThreadPage(c *client, id string, reply bool) (err error) {
+func (s *service) ThreadPage(c *client, id string, reply bool,
+	draftID string) (err error) {
+	return s.threadPage(c, id, reply, draftID, false)
+}
+
+// threadPage renders the thread of a status. The reply form of direct
+// threads is locked to the direct visibility, which can also be forced for
+// other threads.
+func (s *service) threadPage(c *client, id string, reply bool,
+	draftID string, forceDirect bool) (err error) {
 	var pctx model.PostContext
+	c.rctx.FilterContext = "thread"
 
 	status, err := c.GetStatus(c.ctx, id)
 	if err != nil {
@@ -314,17 +603,27 @@ func (s *service) ThreadPage(c *client, id string, reply bool) (err error) {
 			}
 		}
 
-		isDirect := status.Visibility == "direct"
-		if isDirect || c.s.Settings.CopyScope {
+		isDirect := forceDirect || status.Visibility == "direct"
+		if isDirect {
+			visibility = "direct"
+		} else if c.s.Settings.CopyScope {
 			visibility = status.Visibility
 		} else {
 			visibility = c.s.Settings.DefaultVisibility
 		}
 
+		key, err := util.NewIdempotencyKey()
+		if err != nil {
+			return err
+		}
+
 		pctx = model.PostContext{
 			DefaultVisibility: visibility,
 			DefaultFormat:     c.s.Settings.DefaultFormat,
+			DefaultLanguage:   c.s.DefaultLanguage(),
+			IdempotencyKey:    key,
 			Formats:           s.postFormats,
+			Languages:         model.Languages,
 			ReplyContext: &model.ReplyContext{
 				InReplyToID:     id,
 				InReplyToName:   status.Account.Acct,
@@ -332,6 +631,21 @@ func (s *service) ThreadPage(c *client, id string, reply bool) (err error) {
 				ForceVisibility: isDirect,
 			},
 		}
+
+		if len(draftID) > 0 {
+			u, err := c.GetAccountCurrentUser(c.ctx)
+			if err != nil {
+				return err
+			}
+			d, err := s.draftRepo.Get(ownerKey(c, u.ID), draftID)
+			if err != nil {
+				return err
+			}
+			applyDraft(&pctx, &d)
+			if isDirect {
+				pctx.DefaultVisibility = "direct"
+			}
+		}
 	}
 
 	context, err := c.GetStatusContext(c.ctx, id)
@@ -339,7 +653,22 @@ func (s *service) ThreadPage(c *client, id string, reply bool) (err error) {
 		return
 	}
 
+	rules, err := s.muteRules(c)
+	if err != nil {
+		return
+	}
 	statuses := append(append(context.Ancestors, status), context.Descendants...)
+
+	// Hidden statuses which others reply to are collapsed instead, so that
+	// the thread keeps its structure.
+	keep := map[string]bool{status.ID: true}
+	for _, st := range statuses {
+		if id, ok := st.InReplyToID.(string); ok {
+			keep[id] = true
+		}
+	}
+	statuses = applyMuteRules(rules, statuses, c.s.Instance, keep)
+	annotateStatuses(c, statuses)
 	replies := make(map[string][]mastodon.ReplyInfo)
 	idNumbers := make(map[string]int)
 
@@ -353,9 +682,15 @@ func (s *service) ThreadPage(c *client, id string, reply bool) (err error) {
 		addToReplyMap(replies, statuses[i].InReplyToID, statuses[i].ID, i+1)
 	}
 
+	var tree []*renderer.ThreadNode
+	if c.s.Settings.NestedThreads {
+		tree = buildThreadTree(statuses, &pctx)
+	}
+
 	cdata := s.cdata(c, "post by "+status.Account.DisplayName, 0, 0, "")
 	data := &renderer.ThreadData{
 		Statuses:    statuses,
+		Tree:        tree,
 		PostContext: pctx,
 		ReplyMap:    replies,
 		CommonData:  cdata,
@@ -364,6 +699,7 @@ func (s *service) ThreadPage(c *client, id string, reply bool) (err error) {
 }
 
 func (s *service) QuickReplyPage(c *client, id string) (err error) {
+	c.rctx.FilterContext = "thread"
 	status, err := c.GetStatus(c.ctx, id)
 	if err != nil {
 		return
@@ -375,6 +711,9 @@ func (s *service) QuickReplyPage(c *client, id string) (err error) {
 		if err != nil {
 			return
 		}
+		annotateStatuses(c, []*mastodon.Status{ancestor, status})
+	} else {
+		annotateStatuses(c, []*mastodon.Status{status})
 	}
 
 	var content string
@@ -396,10 +735,18 @@ func (s *service) QuickReplyPage(c *client, id string) (err error) {
 		visibility = c.s.Settings.DefaultVisibility
 	}
 
+	key, err := util.NewIdempotencyKey()
+	if err != nil {
+		return
+	}
+
 	pctx := model.PostContext{
 		DefaultVisibility: visibility,
 		DefaultFormat:     c.s.Settings.DefaultFormat,
+		DefaultLanguage:   c.s.DefaultLanguage(),
+		IdempotencyKey:    key,
 		Formats:           s.postFormats,
+		Languages:         model.Languages,
 		ReplyContext: &model.ReplyContext{
 			InReplyToID:     id,
 			InReplyToName:   status.Account.Acct,
@@ -419,6 +766,121 @@ func (s *service) QuickReplyPage(c *client, id string) (err error) {
 	return s.renderer.Render(c.rctx, c.w, renderer.QuickReplyPage, data)
 }
 
+func (s *service) PreviewPage(c *client, content string, replyToID string,
+	format string, visibility string, language string, isNSFW bool,
+	quickReply bool, idempotencyKey string, draftID string) (err error) {
+
+	u, err := c.GetAccountCurrentUser(c.ctx)
+	if err != nil {
+		return
+	}
+
+	var rctx *model.ReplyContext
+	if len(replyToID) > 0 {
+		status, err := c.GetStatus(c.ctx, replyToID)
+		if err != nil {
+			return err
+		}
+		isDirect := status.Visibility == "direct"
+		if isDirect {
+			visibility = status.Visibility
+		}
+		rctx = &model.ReplyContext{
+			InReplyToID:     replyToID,
+			InReplyToName:   status.Account.Acct,
+			QuickReply:      quickReply,
+			ForceVisibility: isDirect,
+		}
+	}
+	if len(visibility) < 1 {
+		visibility = c.s.Settings.DefaultVisibility
+	}
+
+	status, err := s.preview(c, content, replyToID, format, visibility,
+		language, isNSFW)
+	if err != nil {
+		return
+	}
+	status.Account = *u
+
+	pctx := model.PostContext{
+		DefaultVisibility: visibility,
+		DefaultFormat:     format,
+		DefaultContent:    content,
+		DefaultNSFW:       isNSFW,
+		DefaultLanguage:   language,
+		IdempotencyKey:    idempotencyKey,
+		DraftID:           draftID,
+		Formats:           s.postFormats,
+		Languages:         model.Languages,
+		ReplyContext:      rctx,
+	}
+
+	cdata := s.cdata(c, "preview", 0, 0, "")
+	data := &renderer.PreviewData{
+		Status:      status,
+		PostContext: pctx,
+		CommonData:  cdata,
+	}
+	return s.renderer.Render(c.rctx, c.w, renderer.PreviewPage, data)
+}
+
+// preview renders a post without creating it. The instance renders the post
+// if it supports previews, otherwise it is rendered locally, which is only
+// possible for plain text and Markdown.
+func (s *service) preview(c *client, content string, replyToID string,
+	format string, visibility string, language string,
+	isNSFW bool) (*mastodon.Status, error) {
+
+	instance, err := c.GetInstance(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+	if instance.IsPleroma() {
+		return c.PostStatus(c.ctx, &mastodon.Toot{
+			Status:      content,
+			InReplyToID: replyToID,
+			ContentType: format,
+			Visibility:  visibility,
+			Sensitive:   isNSFW,
+			Language:    language,
+			Preview:     true,
+		})
+	}
+
+	var html string
+	switch format {
+	case "", "text/plain":
+		html = renderer.RenderPlainText(content)
+	case "text/markdown":
+		html = renderer.RenderMarkdown(content)
+	default:
+		return nil, errNoPreview
+	}
+	return &mastodon.Status{
+		Content:    html,
+		Visibility: visibility,
+		Sensitive:  isNSFW,
+		Language:   language,
+	}, nil
+}
+
+func (s *service) ProxyMedia(c *client, u string, sig string) (err error) {
+	if s.mediaProxy == nil {
+		return errInvalidArgument
+	}
+	return s.mediaProxy.Serve(c.ctx, c.w, u, sig)
+}
+
+// ProxyCardImage serves the image of a link card, so that the third party
+// site which hosts it doesn't see the IP address of the user.
+func (s *service) ProxyCardImage(c *client, u string, sig string) (err error) {
+	if s.mediaProxy == nil {
+		return errInvalidArgument
+	}
+	return s.mediaProxy.ServeCardImage(c.ctx, c.w, u, sig)
+}
+
 func (s *service) LikedByPage(c *client, id string) (err error) {
 	likers, err := c.GetFavouritedBy(c.ctx, id, nil)
 	if err != nil {
@@ -445,9 +907,21 @@ func (s *service) RetweetedByPage(c *client, id string) (err error) {
 	return s.renderer.Render(c.rctx, c.w, renderer.RetweetedByPage, data)
 }
 
+// notificationTypes are the types of notifications which can be filtered
+// on the notifications page.
+var notificationTypes = []string{"mention", "follow", "follow_request",
+	"reblog", "favourite", "poll"}
+
+// otherNotificationTypes are excluded along with notificationTypes when
+// filtering by type, for servers which ignore include_types.
+var otherNotificationTypes = []string{"status", "update", "move",
+	"admin.sign_up", "admin.report", "pleroma:emoji_reaction",
+	"pleroma:chat_mention"}
+
 func (s *service) NotificationPage(c *client, maxID string,
-	minID string) (err error) {
+	minID string, ntype string, groupKeys []string) (err error) {
 
+	c.rctx.FilterContext = "notifications"
 	var nextLink string
 	var unreadCount int
 	var readID string
@@ -458,52 +932,237 @@ func (s *service) NotificationPage(c *client, maxID string,
 		Limit: 20,
 	}
 
-	if c.s.Settings.HideUnsupportedNotifs {
-		// Explicitly include the supported types.
-		// For now, only Pleroma supports this option, Mastadon
-		// will simply ignore the unknown params.
-		includes = []string{"follow", "follow_request", "mention", "reblog", "favourite"}
-	}
-	if c.s.Settings.AntiDopamineMode {
-		excludes = append(excludes, "follow", "favourite", "reblog")
+	includes, excludes = notificationFilter(&c.s.Settings)
+	if len(ntype) > 0 {
+		var ok bool
+		for _, t := range notificationTypes {
+			if t == ntype {
+				ok = true
+			} else {
+				excludes = append(excludes, t)
+			}
+		}
+		// Types hidden by the settings stay hidden, instead of
+		// being included and excluded at the same time.
+		if !ok || containsString(excludes, ntype) {
+			return errInvalidArgument
+		}
+		includes = []string{ntype}
+		excludes = append(excludes, otherNotificationTypes...)
 	}
 
+	rules, err := s.muteRules(c)
+	if err != nil {
+		return
+	}
 	notifications, err := c.GetNotifications(c.ctx, &pg, includes, excludes)
 	if err != nil {
 		return
 	}
 
-	for i := range notifications {
-		if notifications[i].Pleroma != nil && !notifications[i].Pleroma.IsSeen {
+	// Only Pleroma tells which notifications have been seen, for other
+	// servers the notifications marker is used. Without markers it's
+	// unknown, so none are shown as unread.
+	var lastRead string
+	if len(notifications) > 0 && notifications[0].Pleroma == nil {
+		var ok bool
+		lastRead, ok, err = lastReadID(c, "notifications")
+		if err != nil {
+			c.logError(err)
+		}
+		if err != nil || !ok {
+			lastRead, err = notifications[0].ID, nil
+		}
+	}
+	// The groups of the previous page have already been shown, so the
+	// notifications which belong to them aren't shown again.
+	if len(groupKeys) > maxNotificationGroupKeys {
+		groupKeys = groupKeys[:maxNotificationGroupKeys]
+	}
+	shown := make(map[string]bool)
+	for _, k := range groupKeys {
+		shown[k] = true
+	}
+	groups := groupNotifications(muteNotifications(rules, notifications,
+		c.s.Instance), lastRead, shown)
+
+	// Grouping shrinks the page, so older notifications are fetched until
+	// the page is full again. Paging back up isn't extended, as it would
+	// skip the notifications in between.
+	for i := 0; i < notificationExtraFetches && len(minID) < 1 &&
+		len(groups) < 20 && len(notifications)%20 == 0 &&
+		len(pg.MaxID) > 0 && pg.MaxID != maxID; i++ {
+
+		maxID = pg.MaxID
+		pg = mastodon.Pagination{MaxID: maxID, Limit: 20}
+		more, err := c.GetNotifications(c.ctx, &pg, includes, excludes)
+		if err != nil {
+			return err
+		}
+		notifications = append(notifications, more...)
+		groups = groupNotifications(muteNotifications(rules,
+			notifications, c.s.Instance), lastRead, shown)
+	}
+
+	var statuses []*mastodon.Status
+	for _, g := range groups {
+		if g.Status != nil {
+			statuses = append(statuses, g.Status)
+		}
+	}
+	annotateStatuses(c, statuses)
+
+	for _, n := range notifications {
+		if isUnread(n, lastRead) {
 			unreadCount++
 		}
 	}
 
-	if unreadCount > 0 {
+	// The newest notification of a single type can be older than unread
+	// notifications of other types.
+	if unreadCount > 0 && len(ntype) < 1 {
 		readID = notifications[0].ID
 	}
-	if len(notifications) == 20 && len(pg.MaxID) > 0 {
-		nextLink = "/notifications?max_id=" + pg.MaxID
+	if len(notifications) > 0 && len(notifications)%20 == 0 &&
+		len(pg.MaxID) > 0 {
+		v := make(url.Values)
+		v.Set("max_id", pg.MaxID)
+		if len(ntype) > 0 {
+			v.Set("type", ntype)
+		}
+		v["group"] = nextGroupKeys(groups, groupKeys)
+		nextLink = "/notifications?" + v.Encode()
 	}
 
 	cdata := s.cdata(c, "notifications", unreadCount,
 		c.s.Settings.NotificationInterval, "main")
 	data := &renderer.NotificationData{
-		Notifications: notifications,
+		Notifications: groups,
 		UnreadCount:   unreadCount,
 		ReadID:        readID,
 		NextLink:      nextLink,
+		Type:          ntype,
 		CommonData:    cdata,
 	}
 	return s.renderer.Render(c.rctx, c.w, renderer.NotificationPage, data)
 }
 
+// notificationFilter returns the notification types to include and exclude
+// according to the settings. No types are included if all are allowed.
+func notificationFilter(settings *model.Settings) (includes, excludes []string) {
+	if settings.HideUnsupportedNotifs {
+		// Explicitly include the supported types.
+		// For now, only Pleroma supports this option, Mastadon
+		// will simply ignore the unknown params.
+		includes = []string{"follow", "follow_request", "mention", "reblog", "favourite"}
+	}
+	if settings.AntiDopamineMode {
+		excludes = append(excludes, "follow", "favourite", "reblog")
+	}
+	return
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnread reports whether n hasn't been seen according to Pleroma or is
+// newer than the notification with the id lastRead, which is empty if no
+// notification has been read yet.
+func isUnread(n *mastodon.Notification, lastRead string) bool {
+	if n.Pleroma != nil {
+		return !n.Pleroma.IsSeen
+	}
+	return len(lastRead) < 1 || newerID(n.ID, lastRead)
+}
+
+// notificationGroupKey returns the key of the group n belongs to, or an empty
+// string if n isn't grouped.
+func notificationGroupKey(n *mastodon.Notification) string {
+	if (n.Type == "favourite" || n.Type == "reblog") && n.Status != nil {
+		return n.Type + ":" + n.Status.ID
+	}
+	return ""
+}
+
+// groupNotifications merges the likes and retweets of a status into the
+// newest of them. Notifications of the groups in shown are left out.
+func groupNotifications(notifications []*mastodon.Notification,
+	lastRead string, shown map[string]bool) (
+	groups []*renderer.NotificationGroup) {
+
+	byKey := make(map[string]*renderer.NotificationGroup)
+	for _, n := range notifications {
+		key := notificationGroupKey(n)
+		if len(key) > 0 && shown[key] {
+			continue
+		}
+		if g, ok := byKey[key]; ok && len(key) > 0 {
+			g.IDs = append(g.IDs, n.ID)
+			if isUnread(n, lastRead) {
+				g.Unread = true
+			}
+			seen := false
+			for _, a := range g.Accounts {
+				if a.ID == n.Account.ID {
+					seen = true
+					break
+				}
+			}
+			if !seen {
+				g.Accounts = append(g.Accounts, n.Account)
+			}
+			if len(g.Accounts) > 2 {
+				g.Others = len(g.Accounts) - 2
+			}
+			continue
+		}
+		g := &renderer.NotificationGroup{
+			Notification: n,
+			IDs:          []string{n.ID},
+			Accounts:     []mastodon.Account{n.Account},
+			Unread:       isUnread(n, lastRead),
+		}
+		if len(key) > 0 {
+			byKey[key] = g
+		}
+		groups = append(groups, g)
+	}
+	return
+}
+
+// nextGroupKeys returns the keys of the groups which are continued on the
+// next page, which are the ones of groups and the ones already continued on
+// the current page. Keys of newer groups come first, so that the oldest ones
+// are dropped once there are too many.
+func nextGroupKeys(groups []*renderer.NotificationGroup,
+	groupKeys []string) (keys []string) {
+
+	for _, g := range groups {
+		if key := notificationGroupKey(g.Notification); len(key) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	keys = append(keys, groupKeys...)
+	if len(keys) > maxNotificationGroupKeys {
+		keys = keys[:maxNotificationGroupKeys]
+	}
+	return
+}
+
 func (s *service) UserPage(c *client, id string, pageType string,
-	maxID string, minID string) (err error) {
+	tagged string, maxID string, minID string) (err error) {
 
+	c.rctx.FilterContext = "account"
 	var nextLink string
-	var statuses []*mastodon.Status
+	var statuses, pinned []*mastodon.Status
 	var users []*mastodon.Account
+	var tags []*mastodon.Tag
 	var pg = mastodon.Pagination{
 		MaxID: maxID,
 		MinID: minID,
@@ -515,16 +1174,40 @@ func (s *service) UserPage(c *client, id string, pageType string,
 		return
 	}
 	isCurrent := c.s.UserID == user.ID
+	domain := accountDomain(user)
+
+	// Featured tags are only shown on the main tab and are optional, so a
+	// failure to fetch them doesn't fail the page.
+	var featuredTags []*mastodon.FeaturedTag
+	if len(pageType) < 1 {
+		featuredTags, err = c.GetAccountFeaturedTags(c.ctx, id)
+		if err != nil {
+			if !isNotFound(err) {
+				c.logError(err)
+			}
+			featuredTags, err = nil, nil
+		}
+	}
 
 	switch pageType {
 	case "":
-		statuses, err = c.GetAccountStatuses(c.ctx, id, false, &pg)
+		statuses, err = c.GetAccountStatuses(c.ctx, id, false, tagged, &pg)
 		if err != nil {
 			return
 		}
 		if len(statuses) == 20 && len(pg.MaxID) > 0 {
 			nextLink = fmt.Sprintf("/user/%s?max_id=%s", id,
 				pg.MaxID)
+			if len(tagged) > 0 {
+				nextLink += "&tagged=" + url.QueryEscape(tagged)
+			}
+		}
+		if len(maxID) < 1 && len(minID) < 1 && len(tagged) < 1 {
+			pinned, err = c.GetAccountPinnedStatuses(c.ctx, id)
+			if err != nil {
+				return
+			}
+			statuses = withoutStatuses(statuses, pinned)
 		}
 	case "following":
 		users, err = c.GetAccountFollowing(c.ctx, id, &pg)
@@ -545,7 +1228,7 @@ func (s *service) UserPage(c *client, id string, pageType string,
 				id, pg.MaxID)
 		}
 	case "media":
-		statuses, err = c.GetAccountStatuses(c.ctx, id, true, &pg)
+		statuses, err = c.GetAccountStatuses(c.ctx, id, true, "", &pg)
 		if err != nil {
 			return
 		}
@@ -601,6 +1284,34 @@ func (s *service) UserPage(c *client, id string, pageType string,
 			nextLink = fmt.Sprintf("/user/%s/likes?max_id=%s",
 				id, pg.MaxID)
 		}
+	case "endorsements":
+		if isCurrent {
+			users, err = c.GetEndorsements(c.ctx, &pg)
+		} else {
+			users, err = c.GetAccountEndorsements(c.ctx, id, &pg)
+			if isNotFound(err) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return
+		}
+		if len(users) == 20 && len(pg.MaxID) > 0 {
+			nextLink = fmt.Sprintf("/user/%s/endorsements?max_id=%s",
+				id, pg.MaxID)
+		}
+	case "tags":
+		if !isCurrent {
+			return errInvalidArgument
+		}
+		tags, err = c.GetFollowedTags(c.ctx, &pg)
+		if err != nil {
+			return
+		}
+		if len(tags) == 20 && len(pg.MaxID) > 0 {
+			nextLink = fmt.Sprintf("/user/%s/tags?max_id=%s",
+				id, pg.MaxID)
+		}
 	case "requests":
 		if !isCurrent {
 			return errInvalidArgument
@@ -622,20 +1333,100 @@ func (s *service) UserPage(c *client, id string, pageType string,
 			statuses[i].Reblog.RetweetedByID = statuses[i].ID
 		}
 	}
+	if len(statuses) > 0 || len(pinned) > 0 {
+		rules, err := s.muteRules(c)
+		if err != nil {
+			return err
+		}
+		statuses = applyMuteRules(rules, statuses, c.s.Instance, nil)
+		pinned = applyMuteRules(rules, pinned, c.s.Instance, nil)
+		annotateStatuses(c, append(pinned, statuses...))
+	}
 
 	cdata := s.cdata(c, user.DisplayName+" @"+user.Acct, 0, 0, "")
 	data := &renderer.UserData{
-		User:       user,
-		IsCurrent:  isCurrent,
-		Type:       pageType,
-		Users:      users,
-		Statuses:   statuses,
-		NextLink:   nextLink,
-		CommonData: cdata,
+		User:         user,
+		IsCurrent:    isCurrent,
+		Type:         pageType,
+		Tagged:       tagged,
+		Domain:       domain,
+		Users:        users,
+		Statuses:     statuses,
+		Pinned:       pinned,
+		Tags:         tags,
+		FeaturedTags: featuredTags,
+		NextLink:     nextLink,
+		CommonData:   cdata,
 	}
 	return s.renderer.Render(c.rctx, c.w, renderer.UserPage, data)
 }
 
+// maxRelationships is the number of accounts whose relationships are looked
+// up with a single request.
+const maxRelationships = 40
+
+// annotateStatuses sets the personal notes on the authors of the statuses.
+// The notes are only cosmetic, so they are looked up only if the user has
+// enabled them and the statuses are shown without them if the relationships
+// can't be fetched.
+func annotateStatuses(c *client, statuses []*mastodon.Status) {
+	if !c.s.Settings.ShowAccountNotes {
+		return
+	}
+	var ids []string
+	byAuthor := make(map[string][]*mastodon.Status)
+	for _, st := range statuses {
+		if st.Reblog != nil {
+			st = st.Reblog
+		}
+		id := st.Account.ID
+		if id == c.s.UserID {
+			continue
+		}
+		if _, ok := byAuthor[id]; !ok {
+			ids = append(ids, id)
+		}
+		byAuthor[id] = append(byAuthor[id], st)
+	}
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxRelationships {
+			n = maxRelationships
+		}
+		rs, err := c.GetAccountRelationships(c.ctx, ids[:n])
+		if err != nil {
+			c.logError(err)
+			return
+		}
+		for _, r := range rs {
+			for _, st := range byAuthor[r.ID] {
+				st.AccountNote = r.Note
+			}
+		}
+		ids = ids[n:]
+	}
+}
+
+// withoutStatuses returns statuses without the ones in exclude, so that
+// pinned statuses aren't shown twice.
+func withoutStatuses(statuses []*mastodon.Status,
+	exclude []*mastodon.Status) []*mastodon.Status {
+	if len(exclude) < 1 {
+		return statuses
+	}
+	ids := make(map[string]bool, len(exclude))
+	for _, st := range exclude {
+		ids[st.ID] = true
+	}
+	res := make([]*mastodon.Status, 0, len(statuses))
+	for _, st := range statuses {
+		if !ids[st.ID] {
+			res = append(res, st)
+		}
+	}
+	return res
+}
+
 func (s *service) UserSearchPage(c *client,
 	id string, q string, offset int) (err error) {
 
@@ -738,29 +1529,337 @@ func (s *service) SearchPage(c *client,
 	return s.renderer.Render(c.rctx, c.w, renderer.SearchPage, data)
 }
 
+// historySum returns the uses and accounts in the history of a trend.
+func historySum(history []mastodon.History) (uses int64, accounts int64) {
+	for _, h := range history {
+		uses += int64(h.Uses)
+		accounts += int64(h.Accounts)
+	}
+	return
+}
+
+// ExplorePage shows the trends of the instance, the profile directory and
+// follow suggestions. Instances which don't support them get a notice
+// instead of an error.
+func (s *service) ExplorePage(c *client, eType string, offset int) (err error) {
+	var n int
+	data := &renderer.ExploreData{Type: eType}
+	switch eType {
+	default:
+		return errInvalidArgument
+	case "", "tags":
+		data.Type = "tags"
+		var tags []*mastodon.Tag
+		tags, err = c.GetTrendingTags(c.ctx, offset, 20)
+		for _, t := range tags {
+			uses, accounts := historySum(t.History)
+			data.Tags = append(data.Tags, renderer.TrendingTag{
+				Tag:      t,
+				Uses:     uses,
+				Accounts: accounts,
+			})
+		}
+		n = len(tags)
+	case "statuses":
+		c.rctx.FilterContext = "public"
+		var statuses []*mastodon.Status
+		statuses, err = c.GetTrendingStatuses(c.ctx, offset, 20)
+		if err != nil {
+			break
+		}
+		var rules []*muteRule
+		rules, err = s.muteRules(c)
+		data.Statuses = applyMuteRules(rules, statuses, c.s.Instance, nil)
+		n = len(statuses)
+	case "links":
+		var links []*mastodon.TrendsLink
+		links, err = c.GetTrendingLinks(c.ctx, offset, 20)
+		for _, l := range links {
+			_, accounts := historySum(l.History)
+			data.Links = append(data.Links, renderer.TrendingLink{
+				TrendsLink: l,
+				Accounts:   accounts,
+			})
+		}
+		n = len(links)
+	case "directory":
+		data.Users, err = c.GetDirectory(c.ctx, offset, 20, "active", false)
+		n = len(data.Users)
+	case "suggestions":
+		// Suggestions are a single list without pages.
+		var suggestions []*mastodon.Suggestion
+		suggestions, err = c.GetSuggestions(c.ctx, 40)
+		for _, sg := range suggestions {
+			data.Users = append(data.Users, sg.Account)
+		}
+	}
+	if isNotFound(err) {
+		data.Unsupported = true
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+
+	if n == 20 {
+		data.NextLink = fmt.Sprintf("/explore?type=%s&offset=%d",
+			data.Type, offset+20)
+	}
+	data.CommonData = s.cdata(c, "explore", 0, 0, "")
+	return s.renderer.Render(c.rctx, c.w, renderer.ExplorePage, data)
+}
+
 func (s *service) SettingsPage(c *client) (err error) {
 	cdata := s.cdata(c, "settings", 0, 0, "")
 	data := &renderer.SettingsData{
-		CommonData:  cdata,
-		Settings:    &c.s.Settings,
-		PostFormats: s.postFormats,
+		CommonData:   cdata,
+		Settings:     &c.s.Settings,
+		PostFormats:  s.postFormats,
+		Languages:    model.Languages,
+		ImageMaxSize: s.imageMaxSize,
 	}
 	return s.renderer.Render(c.rctx, c.w, renderer.SettingsPage, data)
 }
 
-func (svc *service) FiltersPage(c *client) (err error) {
-	filters, err := c.GetFilters(c.ctx)
+// filterContexts are the contexts which filters can apply to, in the order
+// of the filter editor.
+var filterContexts = []renderer.FilterContext{
+	{Name: "home", Title: "Home and lists"},
+	{Name: "notifications", Title: "Notifications"},
+	{Name: "public", Title: "Public timelines"},
+	{Name: "thread", Title: "Threads"},
+	{Name: "account", Title: "Profiles"},
+}
+
+const (
+	// filterEditorSlots is the number of empty keyword fields in the
+	// filter editor.
+	filterEditorSlots = 3
+
+	maxFilterKeywords = 100
+)
+
+func (svc *service) FiltersPage(c *client, editID string) (err error) {
+	filters, v1, err := c.GetFiltersV2(c.ctx)
 	if err != nil {
 		return
 	}
+
+	// Servers without the v2 API don't mark filtered statuses, so they
+	// can't be shown with a warning.
+	edit := &mastodon.FilterV2{FilterAction: "warn"}
+	if v1 {
+		edit.FilterAction = "hide"
+	}
+	for _, fc := range filterContexts {
+		edit.Context = append(edit.Context, fc.Name)
+	}
+	if len(editID) > 0 {
+		var found bool
+		for _, f := range filters {
+			if f.ID == editID {
+				f := *f
+				edit, found = &f, true
+				if v1 {
+					edit.FilterAction = "hide"
+				}
+				break
+			}
+		}
+		if !found {
+			return errInvalidArgument
+		}
+	}
+	keywords := make([]mastodon.FilterKeyword, len(edit.Keywords),
+		len(edit.Keywords)+filterEditorSlots)
+	copy(keywords, edit.Keywords)
+	for i := 0; i < filterEditorSlots; i++ {
+		keywords = append(keywords, mastodon.FilterKeyword{WholeWord: true})
+	}
+	edit.Keywords = keywords
+
+	contexts := make([]renderer.FilterContext, len(filterContexts))
+	for i, fc := range filterContexts {
+		fc.Selected = containsString(edit.Context, fc.Name)
+		contexts[i] = fc
+	}
+
 	cdata := svc.cdata(c, "filters", 0, 0, "")
 	data := &renderer.FiltersData{
 		CommonData: cdata,
 		Filters:    filters,
+		Edit:       edit,
+		Contexts:   contexts,
+		HideOnly:   v1,
 	}
 	return svc.renderer.Render(c.rctx, c.w, renderer.FiltersPage, data)
 }
 
+func (s *service) DraftsPage(c *client) (err error) {
+	// The user id in the session can't be trusted, so the drafts are
+	// looked up with the id of the account which owns the access token.
+	u, err := c.GetAccountCurrentUser(c.ctx)
+	if err != nil {
+		return
+	}
+	drafts, err := s.draftRepo.List(ownerKey(c, u.ID))
+	if err != nil {
+		return
+	}
+	cdata := s.cdata(c, "drafts", 0, 0, "")
+	data := &renderer.DraftsData{
+		CommonData: cdata,
+		Drafts:     drafts,
+	}
+	return s.renderer.Render(c.rctx, c.w, renderer.DraftsPage, data)
+}
+
+func (s *service) ConversationsPage(c *client, maxID string) (err error) {
+	var nextLink string
+	var pg = mastodon.Pagination{
+		MaxID: maxID,
+		Limit: 20,
+	}
+	conversations, err := c.GetConversations(c.ctx, &pg)
+	if err != nil {
+		return
+	}
+
+	var unreadCount int
+	for _, cv := range conversations {
+		if cv.Unread {
+			unreadCount++
+		}
+	}
+
+	if len(pg.MaxID) > 0 && len(conversations) == 20 {
+		nextLink = "/conversations?max_id=" + pg.MaxID
+	}
+
+	cdata := s.cdata(c, "conversations", 0, 0, "")
+	data := &renderer.ConversationsData{
+		CommonData:    cdata,
+		Conversations: conversations,
+		UnreadCount:   unreadCount,
+		NextLink:      nextLink,
+	}
+	return s.renderer.Render(c.rctx, c.w, renderer.ConversationsPage, data)
+}
+
+func (s *service) DomainBlocksPage(c *client, maxID string) (err error) {
+	var nextLink string
+	var pg = mastodon.Pagination{
+		MaxID: maxID,
+		Limit: 40,
+	}
+	domains, err := c.GetDomainBlocks(c.ctx, &pg)
+	if err != nil {
+		return
+	}
+	if len(pg.MaxID) > 0 && len(domains) == 40 {
+		nextLink = "/domainblocks?max_id=" + pg.MaxID
+	}
+
+	cdata := s.cdata(c, "domain blocks", 0, 0, "")
+	data := &renderer.DomainBlocksData{
+		CommonData: cdata,
+		Domains:    domains,
+		NextLink:   nextLink,
+	}
+	return s.renderer.Render(c.rctx, c.w, renderer.DomainBlocksPage, data)
+}
+
+// reportCategories are the categories of reports which can be chosen, besides
+// "violation" which needs the rules of the instance.
+var reportCategories = map[string]bool{"spam": true, "other": true}
+
+func (s *service) ReportPage(c *client, id string, statusID string) (err error) {
+	user, err := c.GetAccount(c.ctx, id)
+	if err != nil {
+		return
+	}
+	if user.ID == c.s.UserID {
+		return errInvalidArgument
+	}
+	pg := mastodon.Pagination{Limit: 20}
+	recent, err := c.GetAccountStatuses(c.ctx, id, false, "", &pg)
+	if err != nil {
+		return
+	}
+	// Retweets can't be reported as statuses of the account.
+	var statuses []*mastodon.Status
+	var found bool
+	for _, st := range recent {
+		if st.Reblog != nil {
+			continue
+		}
+		if st.ID == statusID {
+			found = true
+		}
+		statuses = append(statuses, st)
+	}
+	if len(statusID) > 0 && !found {
+		st, err := c.GetStatus(c.ctx, statusID)
+		if err != nil {
+			return err
+		}
+		if st.Account.ID == user.ID {
+			statuses = append([]*mastodon.Status{st}, statuses...)
+		}
+	}
+	rules, err := c.GetInstanceRules(c.ctx)
+	if isNotFound(err) {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+
+	cdata := s.cdata(c, "report "+user.Acct, 0, 0, "")
+	data := &renderer.ReportData{
+		CommonData: cdata,
+		User:       user,
+		Domain:     accountDomain(user),
+		Statuses:   statuses,
+		StatusID:   statusID,
+		Rules:      rules,
+	}
+	return s.renderer.Render(c.rctx, c.w, renderer.ReportPage, data)
+}
+
+func (s *service) ReportedPage(c *client, id string, forwarded bool) (err error) {
+	user, err := c.GetAccount(c.ctx, id)
+	if err != nil {
+		return
+	}
+	cdata := s.cdata(c, "reported "+user.Acct, 0, 0, "")
+	data := &renderer.ReportedData{
+		CommonData: cdata,
+		User:       user,
+		Domain:     accountDomain(user),
+		Forwarded:  forwarded,
+	}
+	return s.renderer.Render(c.rctx, c.w, renderer.ReportedPage, data)
+}
+
+// ConversationPage marks a conversation as read and renders the thread of
+// its last status, so that the user can reply to it right away.
+func (s *service) ConversationPage(c *client, id string,
+	statusID string) (err error) {
+	if len(statusID) < 1 {
+		return errInvalidArgument
+	}
+	return s.threadPage(c, statusID, true, "", true)
+}
+
+func (s *service) ReadConversation(c *client, id string) (err error) {
+	return c.MarkConversationAsRead(c.ctx, id)
+}
+
+func (s *service) RemoveConversation(c *client, id string) (err error) {
+	return c.DeleteConversation(c.ctx, id)
+}
+
 func (s *service) SingleInstance() (instance string, ok bool) {
 	if len(s.instance) > 0 {
 		instance = s.instance
@@ -837,16 +1936,78 @@ func (s *service) Signin(c *client, code string) (err error) {
 	}
 	c.s.AccessToken = c.GetAccessToken(c.ctx)
 	c.s.UserID = u.ID
+	if u.Source != nil && u.Source.Language != nil {
+		c.s.Language = *u.Source.Language
+	}
 	return c.setSession(c.s)
 }
 
 func (s *service) Post(c *client, content string, replyToID string,
-	format string, visibility string, isNSFW bool,
+	replyToName string, format string, visibility string, language string,
+	isNSFW bool, idempotencyKey string, draftID string,
 	files []*multipart.FileHeader) (id string, err error) {
 
+	defer func() {
+		if err == nil {
+			if len(draftID) > 0 {
+				owner, err := s.accountOwner(c)
+				if err == nil {
+					s.draftRepo.Remove(owner, draftID)
+				}
+			}
+			return
+		}
+		// There is no point in keeping a draft for a session which
+		// the instance doesn't accept.
+		if isAuthError(err) || len(strings.TrimSpace(content)) < 1 {
+			return
+		}
+		// The owner is looked up with the access token, as the user id of
+		// the session cookie can be set to anything.
+		owner, oerr := s.accountOwner(c)
+		if oerr != nil {
+			return
+		}
+		d := &model.Draft{
+			ID:            draftID,
+			Content:       content,
+			Format:        format,
+			Visibility:    visibility,
+			IsNSFW:        isNSFW,
+			Language:      language,
+			InReplyToID:   replyToID,
+			InReplyToName: replyToName,
+			UpdatedAt:     time.Now(),
+		}
+		if len(d.ID) < 1 {
+			d.ID, _ = util.NewDraftID()
+		}
+		if s.draftRepo.Add(owner, *d) == nil {
+			err = &draftError{err, d}
+		}
+	}()
+
+	if len(idempotencyKey) > 0 {
+		// Keys are looked up by the account which owns the access
+		// token, as the session can be modified by the client.
+		var owner string
+		owner, err = s.accountOwner(c)
+		if err != nil {
+			return
+		}
+		key := owner + ":" + idempotencyKey
+		id, err = s.postCache.claim(c.ctx, key)
+		if err != nil || len(id) > 0 {
+			return
+		}
+		defer func() {
+			s.postCache.release(key, id)
+		}()
+	}
+
 	var mediaIDs []string
 	for _, f := range files {
-		a, err := c.UploadMediaFromMultipartFileHeader(c.ctx, f)
+		a, err := s.uploadMedia(c, f)
 		if err != nil {
 			return "", err
 		}
@@ -854,12 +2015,14 @@ func (s *service) Post(c *client, content string, replyToID string,
 	}
 
 	tweet := &mastodon.Toot{
-		Status:      content,
-		InReplyToID: replyToID,
-		MediaIDs:    mediaIDs,
-		ContentType: format,
-		Visibility:  visibility,
-		Sensitive:   isNSFW,
+		Status:         content,
+		InReplyToID:    replyToID,
+		MediaIDs:       mediaIDs,
+		ContentType:    format,
+		Visibility:     visibility,
+		Sensitive:      isNSFW,
+		Language:       language,
+		IdempotencyKey: idempotencyKey,
 	}
 	st, err := c.PostStatus(c.ctx, tweet)
 	if err != nil {
@@ -868,6 +2031,86 @@ func (s *service) Post(c *client, content string, replyToID string,
 	return st.ID, nil
 }
 
+// uploadMedia uploads an attachment after removing the metadata from images,
+// which can contain the location where a photo was taken, and waits until
+// the instance has processed it.
+func (s *service) uploadMedia(c *client, fh *multipart.FileHeader) (
+	a *mastodon.Attachment, err error) {
+
+	defer func() {
+		if err != nil {
+			err = &mediaError{fh.Filename, err}
+		}
+	}()
+
+	f, err := fh.Open()
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return
+	}
+	maxSize := s.imageMaxSize
+	if c.s.Settings.KeepOriginalImages {
+		maxSize = 0
+	}
+	data, err = media.Clean(data, maxSize)
+	if err != nil {
+		return
+	}
+	a, err = c.UploadMediaFromNamedReader(c.ctx, fh.Filename,
+		bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, mediaProcessingTimeout)
+	defer cancel()
+	for len(a.URL) < 1 {
+		select {
+		case <-time.After(mediaPollInterval):
+		case <-ctx.Done():
+			if c.ctx.Err() != nil {
+				return nil, c.ctx.Err()
+			}
+			return nil, errMediaTimeout
+		}
+		a, err = c.GetMedia(ctx, a.ID)
+		if err != nil {
+			if ctx.Err() != nil && c.ctx.Err() == nil {
+				err = errMediaTimeout
+			}
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func applyDraft(pctx *model.PostContext, d *model.Draft) {
+	pctx.DraftID = d.ID
+	pctx.DefaultContent = d.Content
+	pctx.DefaultNSFW = d.IsNSFW
+	if len(d.Format) > 0 {
+		pctx.DefaultFormat = d.Format
+	}
+	if len(d.Visibility) > 0 {
+		pctx.DefaultVisibility = d.Visibility
+	}
+	if len(d.Language) > 0 {
+		pctx.DefaultLanguage = d.Language
+	}
+}
+
+func (s *service) RemoveDraft(c *client, id string) (err error) {
+	u, err := c.GetAccountCurrentUser(c.ctx)
+	if err != nil {
+		return
+	}
+	return s.draftRepo.Remove(ownerKey(c, u.ID), id)
+}
+
 func (s *service) Like(c *client, id string) (count int64, err error) {
 	st, err := c.Favourite(c.ctx, id)
 	if err != nil {
@@ -960,6 +2203,119 @@ func (s *service) UnSubscribe(c *client, id string) (err error) {
 	return
 }
 
+// accountDomain returns the domain of the account if it's remote.
+func accountDomain(a *mastodon.Account) string {
+	if i := strings.LastIndex(a.Acct, "@"); i >= 0 {
+		return a.Acct[i+1:]
+	}
+	return ""
+}
+
+// normalizeDomain returns the host name of domain, which can also be given
+// as a URL or an account address.
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if i := strings.Index(domain, "://"); i >= 0 {
+		domain = domain[i+3:]
+	}
+	if i := strings.IndexAny(domain, "/?#"); i >= 0 {
+		domain = domain[:i]
+	}
+	if i := strings.LastIndex(domain, "@"); i >= 0 {
+		domain = domain[i+1:]
+	}
+	return domain
+}
+
+func (s *service) BlockDomain(c *client, domain string) (err error) {
+	domain = normalizeDomain(domain)
+	if len(domain) < 1 {
+		return errInvalidArgument
+	}
+	return c.BlockDomain(c.ctx, domain)
+}
+
+func (s *service) UnBlockDomain(c *client, domain string) (err error) {
+	domain = normalizeDomain(domain)
+	if len(domain) < 1 {
+		return errInvalidArgument
+	}
+	return c.UnblockDomain(c.ctx, domain)
+}
+
+// Report sends the report to the moderators and returns whether it was also
+// forwarded to the instance of the account.
+func (s *service) Report(c *client, id string, statusIDs []string, category string,
+	ruleIDs []string, comment string, forward bool) (forwarded bool, err error) {
+	switch {
+	case category == "violation" && len(ruleIDs) > 0:
+	case reportCategories[category]:
+		ruleIDs = nil
+	default:
+		return false, errInvalidArgument
+	}
+	r, err := c.Report(c.ctx, id, statusIDs, strings.TrimSpace(comment),
+		category, ruleIDs, forward)
+	if err != nil {
+		return
+	}
+	return r.Forwarded, nil
+}
+
+func (s *service) SetNote(c *client, id string, note string) (err error) {
+	_, err = c.SetAccountNote(c.ctx, id, strings.TrimSpace(note))
+	return
+}
+
+func (s *service) Pin(c *client, id string) (err error) {
+	_, err = c.Pin(c.ctx, id)
+	return
+}
+
+func (s *service) UnPin(c *client, id string) (err error) {
+	_, err = c.Unpin(c.ctx, id)
+	return
+}
+
+func (s *service) Endorse(c *client, id string) (err error) {
+	_, err = c.AccountEndorse(c.ctx, id)
+	return
+}
+
+func (s *service) UnEndorse(c *client, id string) (err error) {
+	_, err = c.AccountUnendorse(c.ctx, id)
+	return
+}
+
+func (s *service) FollowTag(c *client, name string) (err error) {
+	if !isTagName(name) {
+		return errInvalidArgument
+	}
+	_, err = c.FollowTag(c.ctx, name)
+	return
+}
+
+func (s *service) UnFollowTag(c *client, name string) (err error) {
+	if !isTagName(name) {
+		return errInvalidArgument
+	}
+	_, err = c.UnfollowTag(c.ctx, name)
+	return
+}
+
+func (s *service) FeatureTag(c *client, name string) (err error) {
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if len(name) < 1 {
+		return errInvalidArgument
+	}
+	_, err = c.FeatureTag(c.ctx, name)
+	return
+}
+
+func (s *service) UnFeatureTag(c *client, id string) (err error) {
+	return c.UnfeatureTag(c.ctx, id)
+}
+
 func (s *service) SaveSettings(c *client, settings *model.Settings) (err error) {
 	switch settings.NotificationInterval {
 	case 0, 30, 60, 120, 300, 600:
@@ -991,6 +2347,20 @@ func (s *service) ReadNotifications(c *client, maxID string) (err error) {
 	return c.ReadNotifications(c.ctx, maxID)
 }
 
+func (s *service) ClearNotifications(c *client) (err error) {
+	return c.ClearNotifications(c.ctx)
+}
+
+func (s *service) DismissNotification(c *client, ids []string) (err error) {
+	for _, id := range ids {
+		err = c.DismissNotification(c.ctx, id)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 func (s *service) Bookmark(c *client, id string) (err error) {
 	_, err = c.Bookmark(c.ctx, id)
 	return
@@ -1001,11 +2371,63 @@ func (s *service) UnBookmark(c *client, id string) (err error) {
 	return
 }
 
-func (svc *service) Filter(c *client, phrase string, wholeWord bool) (err error) {
-	fctx := []string{"home", "notifications", "public", "thread"}
-	return c.AddFilter(c.ctx, phrase, fctx, true, wholeWord, nil)
+// Filter creates a filter, or updates the filter with the given id. Blank
+// keywords are ignored, or removed if they already exist.
+func (svc *service) Filter(c *client, id string,
+	p *mastodon.FilterParams) (err error) {
+
+	var keywords []mastodon.FilterKeyword
+	var count int
+	for _, k := range p.Keywords {
+		k.Keyword = strings.TrimSpace(k.Keyword)
+		if len(k.Keyword) < 1 {
+			if len(k.ID) < 1 {
+				continue
+			}
+			k.Destroy = true
+		}
+		if !k.Destroy {
+			count++
+		}
+		keywords = append(keywords, k)
+	}
+	if count < 1 {
+		return errInvalidArgument
+	}
+	p.Keywords = keywords
+
+	for _, fctx := range p.Context {
+		var ok bool
+		for _, fc := range filterContexts {
+			ok = ok || fc.Name == fctx
+		}
+		if !ok {
+			return errInvalidArgument
+		}
+	}
+	if len(p.Context) < 1 {
+		return errInvalidArgument
+	}
+	if p.FilterAction != "warn" && p.FilterAction != "hide" {
+		return errInvalidArgument
+	}
+
+	p.Title = strings.TrimSpace(p.Title)
+	if len(p.Title) < 1 {
+		for _, k := range keywords {
+			if !k.Destroy {
+				p.Title = k.Keyword
+				break
+			}
+		}
+	}
+
+	if len(id) > 0 {
+		return c.UpdateFilterV2(c.ctx, id, p)
+	}
+	return c.CreateFilterV2(c.ctx, p)
 }
 
 func (svc *service) UnFilter(c *client, id string) (err error) {
-	return c.RemoveFilter(c.ctx, id)
+	return c.DeleteFilterV2(c.ctx, id)
 }
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"bloat/mastodon"
+	"bloat/renderer"
+)
+
+const streamKeepAliveInterval = 30 * time.Second
+
+var errStreamingUnsupported = errors.New("streaming is not supported")
+
+// writeEvent writes a server-sent event. Every line of data is sent as a
+// separate data field, which the browser joins again.
+func writeEvent(w io.Writer, name string, data string) (err error) {
+	var b strings.Builder
+	b.WriteString("event: " + name + "\n")
+	for _, l := range strings.Split(data, "\n") {
+		b.WriteString("data: " + l + "\n")
+	}
+	b.WriteString("\n")
+	_, err = io.WriteString(w, b.String())
+	return
+}
+
+// streamEvents relays the events of the stream returned by open as
+// server-sent events, until either the client or the server closes the
+// connection. The stream is closed when the client goes away.
+func streamEvents(c *client,
+	open func(ctx context.Context) (chan mastodon.Event, error),
+	write func(w io.Writer, e mastodon.Event) error) (err error) {
+
+	f, ok := c.w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return errStreamingUnsupported
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	events, err := open(ctx)
+	if err != nil {
+		return
+	}
+
+	c.w.Header().Set("Cache-Control", "no-cache")
+	c.w.WriteHeader(http.StatusOK)
+	_, err = io.WriteString(c.w, "retry: 10000\n\n")
+	if err != nil {
+		return
+	}
+	f.Flush()
+
+	t := time.NewTicker(streamKeepAliveInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			_, err = io.WriteString(c.w, ": keepalive\n\n")
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			// The stream reconnects by itself after errors.
+			if _, ok := e.(*mastodon.ErrorEvent); ok {
+				continue
+			}
+			err = write(c.w, e)
+		}
+		if err != nil {
+			return
+		}
+		f.Flush()
+	}
+}
+
+// StreamNotifications relays the notifications of the user as server-sent
+// events.
+func (s *service) StreamNotifications(c *client) (err error) {
+	includes, excludes := notificationFilter(&c.s.Settings)
+	return streamEvents(c, c.StreamingUser,
+		func(w io.Writer, e mastodon.Event) error {
+			ne, ok := e.(*mastodon.NotificationEvent)
+			if !ok {
+				return nil
+			}
+			n := ne.Notification
+			if len(includes) > 0 && !containsString(includes, n.Type) ||
+				containsString(excludes, n.Type) {
+				return nil
+			}
+			data, err := json.Marshal(map[string]string{
+				"id":   n.ID,
+				"type": n.Type,
+			})
+			if err != nil {
+				return err
+			}
+			return writeEvent(w, "notification", string(data))
+		})
+}
+
+// StreamTimeline relays the new and deleted statuses of a timeline as
+// server-sent events. New statuses are sent rendered, so that they can be
+// inserted into the timeline page as they are.
+func (s *service) StreamTimeline(c *client, tType string,
+	listID string, tag string) (err error) {
+
+	var open func(ctx context.Context) (chan mastodon.Event, error)
+	v := make(url.Values)
+	switch tType {
+	default:
+		return errInvalidArgument
+	case "home":
+		open = c.StreamingUser
+	case "local", "twkn":
+		open = func(ctx context.Context) (chan mastodon.Event, error) {
+			return c.StreamingPublic(ctx, tType == "local")
+		}
+	case "list":
+		if len(listID) < 1 {
+			return errInvalidArgument
+		}
+		open = func(ctx context.Context) (chan mastodon.Event, error) {
+			return c.StreamingList(ctx, listID)
+		}
+		v.Set("list", listID)
+	case "tag":
+		if len(tag) < 1 {
+			return errInvalidArgument
+		}
+		open = func(ctx context.Context) (chan mastodon.Event, error) {
+			return c.StreamingHashtag(ctx, tag, false)
+		}
+		v.Set("tag", tag)
+	}
+
+	c.rctx.FilterContext = timelineFilterContext(tType)
+	rules, err := s.muteRules(c)
+	if err != nil {
+		return
+	}
+
+	// Forms of the rendered statuses return to the timeline page.
+	c.rctx.Referrer = "/timeline/" + tType
+	if len(v) > 0 {
+		c.rctx.Referrer += "?" + v.Encode()
+	}
+
+	var buf bytes.Buffer
+	notes := make(noteCache)
+	return streamEvents(c, open, func(w io.Writer, e mastodon.Event) error {
+		switch e := e.(type) {
+		case *mastodon.UpdateEvent:
+			st := e.Status
+			if st.Reblog != nil {
+				st.Reblog.RetweetedByID = st.ID
+			}
+			if len(applyMuteRules(rules, []*mastodon.Status{st},
+				c.s.Instance, nil)) < 1 {
+				return nil
+			}
+			notes.annotate(c, st)
+			buf.Reset()
+			err := s.renderer.Render(c.rctx, &buf, renderer.StatusFragment, st)
+			if err != nil {
+				return err
+			}
+			return writeEvent(w, "update", buf.String())
+		case *mastodon.DeleteEvent:
+			return writeEvent(w, "delete", e.ID)
+		}
+		return nil
+	})
+}
+
+// noteCacheSize is the number of authors whose notes are remembered by a
+// stream.
+const noteCacheSize = 1024
+
+// noteCache remembers the personal notes on the authors of streamed
+// statuses, so that the relationship of an author is only looked up the
+// first time one of their statuses is streamed.
+type noteCache map[string]string
+
+func (nc noteCache) annotate(c *client, st *mastodon.Status) {
+	if st.Reblog != nil {
+		st = st.Reblog
+	}
+	id := st.Account.ID
+	if note, ok := nc[id]; ok {
+		st.AccountNote = note
+		return
+	}
+	annotateStatuses(c, []*mastodon.Status{st})
+	if len(nc) >= noteCacheSize {
+		for k := range nc {
+			delete(nc, k)
+		}
+	}
+	nc[id] = st.AccountNote
+}
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"time"
 
+	"bloat/mastodon"
+	"bloat/mediaproxy"
 	"bloat/model"
 
 	"github.com/gorilla/mux"
@@ -15,6 +17,8 @@ import (
 const (
 	HTML int = iota
 	JSON
+	MEDIA
+	SSE
 )
 
 const (
@@ -27,6 +31,11 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 	r := mux.NewRouter()
 
 	writeError := func(c *client, err error, t int, retry bool) {
+		// Errors of responses which have already been started, such as
+		// streams which fail midway, are only logged.
+		if c.w.started {
+			return
+		}
 		switch t {
 		case HTML:
 			c.w.WriteHeader(http.StatusInternalServerError)
@@ -36,6 +45,8 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 			json.NewEncoder(c.w).Encode(map[string]string{
 				"error": err.Error(),
 			})
+		case MEDIA, SSE:
+			c.w.WriteHeader(http.StatusBadGateway)
 		}
 	}
 
@@ -43,9 +54,10 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		return func(w http.ResponseWriter, req *http.Request) {
 			var err error
 			c := &client{
-				ctx: req.Context(),
-				w:   w,
-				r:   req,
+				ctx:    req.Context(),
+				w:      &responseWriter{ResponseWriter: w},
+				r:      req,
+				logger: logger,
 			}
 
 			defer func(begin time.Time) {
@@ -53,14 +65,19 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 					req.URL.Path, err, time.Since(begin))
 			}(time.Now())
 
+			// Media handlers set the content type themselves.
 			var ct string
 			switch rt {
 			case HTML:
 				ct = "text/html; charset=utf-8"
 			case JSON:
 				ct = "application/json"
+			case SSE:
+				ct = "text/event-stream"
+			}
+			if len(ct) > 0 {
+				c.w.Header().Add("Content-Type", ct)
 			}
-			c.w.Header().Add("Content-Type", ct)
 
 			err = c.authenticate(at)
 			if err != nil {
@@ -93,7 +110,9 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 	}, NOAUTH, HTML)
 
 	navPage := handle(func(c *client) error {
-		return s.NavPage(c)
+		q := c.r.URL.Query()
+		draftID := q.Get("draft")
+		return s.NavPage(c, draftID)
 	}, SESSION, HTML)
 
 	signinPage := handle(func(c *client) error {
@@ -115,9 +134,10 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		q := c.r.URL.Query()
 		instance := q.Get("instance")
 		list := q.Get("list")
+		tag := q.Get("tag")
 		maxID := q.Get("max_id")
 		minID := q.Get("min_id")
-		return s.TimelinePage(c, tType, instance, list, maxID, minID)
+		return s.TimelinePage(c, tType, instance, list, tag, maxID, minID)
 	}, SESSION, HTML)
 
 	defaultTimelinePage := handle(func(c *client) error {
@@ -129,7 +149,8 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		id, _ := mux.Vars(c.r)["id"]
 		q := c.r.URL.Query()
 		reply := q.Get("reply")
-		return s.ThreadPage(c, id, len(reply) > 1)
+		draftID := q.Get("draft")
+		return s.ThreadPage(c, id, len(reply) > 1, draftID)
 	}, SESSION, HTML)
 
 	quickReplyPage := handle(func(c *client) error {
@@ -151,16 +172,30 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		q := c.r.URL.Query()
 		maxID := q.Get("max_id")
 		minID := q.Get("min_id")
-		return s.NotificationPage(c, maxID, minID)
+		ntype := q.Get("type")
+		groupKeys := q["group"]
+		return s.NotificationPage(c, maxID, minID, ntype, groupKeys)
+	}, SESSION, HTML)
+
+	conversationsPage := handle(func(c *client) error {
+		maxID := c.r.URL.Query().Get("max_id")
+		return s.ConversationsPage(c, maxID)
+	}, SESSION, HTML)
+
+	conversationPage := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		statusID := c.r.URL.Query().Get("status")
+		return s.ConversationPage(c, id, statusID)
 	}, SESSION, HTML)
 
 	userPage := handle(func(c *client) error {
 		id, _ := mux.Vars(c.r)["id"]
 		pageType, _ := mux.Vars(c.r)["type"]
 		q := c.r.URL.Query()
+		tagged := q.Get("tagged")
 		maxID := q.Get("max_id")
 		minID := q.Get("min_id")
-		return s.UserPage(c, id, pageType, maxID, minID)
+		return s.UserPage(c, id, pageType, tagged, maxID, minID)
 	}, SESSION, HTML)
 
 	userSearchPage := handle(func(c *client) error {
@@ -187,12 +222,24 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		return s.SearchPage(c, sq, qType, offset)
 	}, SESSION, HTML)
 
+	explorePage := handle(func(c *client) error {
+		q := c.r.URL.Query()
+		eType := q.Get("type")
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		return s.ExplorePage(c, eType, offset)
+	}, SESSION, HTML)
+
 	settingsPage := handle(func(c *client) error {
 		return s.SettingsPage(c)
 	}, SESSION, HTML)
 
 	filtersPage := handle(func(c *client) error {
-		return s.FiltersPage(c)
+		edit := c.r.URL.Query().Get("edit")
+		return s.FiltersPage(c, edit)
+	}, SESSION, HTML)
+
+	muteRulesPage := handle(func(c *client) error {
+		return s.MuteRulesPage(c)
 	}, SESSION, HTML)
 
 	signin := handle(func(c *client) error {
@@ -220,13 +267,27 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 	post := handle(func(c *client) error {
 		content := c.r.FormValue("content")
 		replyToID := c.r.FormValue("reply_to_id")
+		replyToName := c.r.FormValue("reply_to_name")
 		format := c.r.FormValue("format")
 		visibility := c.r.FormValue("visibility")
+		language := c.r.FormValue("language")
 		isNSFW := c.r.FormValue("is_nsfw") == "true"
 		quickReply := c.r.FormValue("quickreply") == "true"
+		idempotencyKey := c.r.FormValue("idempotency_key")
+		draftID := c.r.FormValue("draft_id")
 		files := c.r.MultipartForm.File["attachments"]
 
-		id, err := s.Post(c, content, replyToID, format, visibility, isNSFW, files)
+		if c.r.FormValue("preview") == "true" {
+			// Forms on the preview page should lead back to the page
+			// the post was composed on.
+			c.rctx.Referrer = c.r.FormValue("referrer")
+			return s.PreviewPage(c, content, replyToID, format,
+				visibility, language, isNSFW, quickReply,
+				idempotencyKey, draftID)
+		}
+
+		id, err := s.Post(c, content, replyToID, replyToName, format,
+			visibility, language, isNSFW, idempotencyKey, draftID, files)
 		if err != nil {
 			return err
 		}
@@ -425,6 +486,157 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		return nil
 	}, CSRF, HTML)
 
+	domainBlocksPage := handle(func(c *client) error {
+		q := c.r.URL.Query()
+		maxID := q.Get("max_id")
+		return s.DomainBlocksPage(c, maxID)
+	}, SESSION, HTML)
+
+	blockDomain := handle(func(c *client) error {
+		domain := c.r.FormValue("domain")
+		err := s.BlockDomain(c, domain)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	unBlockDomain := handle(func(c *client) error {
+		domain := c.r.FormValue("domain")
+		err := s.UnBlockDomain(c, domain)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	reportPage := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		q := c.r.URL.Query()
+		statusID := q.Get("status_id")
+		return s.ReportPage(c, id, statusID)
+	}, SESSION, HTML)
+
+	reportedPage := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		q := c.r.URL.Query()
+		forwarded := q.Get("forwarded") == "true"
+		return s.ReportedPage(c, id, forwarded)
+	}, SESSION, HTML)
+
+	report := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		statusIDs, _ := c.r.PostForm["status_ids"]
+		category := c.r.FormValue("category")
+		ruleIDs, _ := c.r.PostForm["rule_ids"]
+		comment := c.r.FormValue("comment")
+		forward := c.r.FormValue("forward") == "true"
+		forwarded, err := s.Report(c, id, statusIDs, category, ruleIDs,
+			comment, forward)
+		if err != nil {
+			return err
+		}
+		url := "/report/" + id + "/done"
+		if forwarded {
+			url += "?forwarded=true"
+		}
+		c.redirect(url)
+		return nil
+	}, CSRF, HTML)
+
+	setNote := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		note := c.r.FormValue("note")
+		err := s.SetNote(c, id, note)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	pin := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		err := s.Pin(c, id)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer") + "#status-" + id)
+		return nil
+	}, CSRF, HTML)
+
+	unPin := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		err := s.UnPin(c, id)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer") + "#status-" + id)
+		return nil
+	}, CSRF, HTML)
+
+	endorse := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		err := s.Endorse(c, id)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	unEndorse := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		err := s.UnEndorse(c, id)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	followTag := handle(func(c *client) error {
+		name, _ := mux.Vars(c.r)["name"]
+		err := s.FollowTag(c, name)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	unFollowTag := handle(func(c *client) error {
+		name, _ := mux.Vars(c.r)["name"]
+		err := s.UnFollowTag(c, name)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	featureTag := handle(func(c *client) error {
+		name := c.r.FormValue("name")
+		err := s.FeatureTag(c, name)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	unFeatureTag := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		err := s.UnFeatureTag(c, id)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
 	settings := handle(func(c *client) error {
 		visibility := c.r.FormValue("visibility")
 		format := c.r.FormValue("format")
@@ -438,6 +650,12 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		antiDopamineMode := c.r.FormValue("anti_dopamine_mode") == "true"
 		hideUnsupportedNotifs := c.r.FormValue("hide_unsupported_notifs") == "true"
 		css := c.r.FormValue("css")
+		keepOriginalImages := c.r.FormValue("keep_original_images") == "true"
+		language := c.r.FormValue("language")
+		proxyCardImages := c.r.FormValue("proxy_card_images") == "true"
+		reduceMotion := c.r.FormValue("reduce_motion") == "true"
+		nestedThreads := c.r.FormValue("nested_threads") == "true"
+		showAccountNotes := c.r.FormValue("show_account_notes") == "true"
 
 		settings := &model.Settings{
 			DefaultVisibility:     visibility,
@@ -452,6 +670,12 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 			AntiDopamineMode:      antiDopamineMode,
 			HideUnsupportedNotifs: hideUnsupportedNotifs,
 			CSS:                   css,
+			KeepOriginalImages:    keepOriginalImages,
+			DefaultLanguage:       language,
+			ProxyCardImages:       proxyCardImages,
+			ReduceMotion:          reduceMotion,
+			NestedThreads:         nestedThreads,
+			ShowAccountNotes:      showAccountNotes,
 		}
 
 		err := s.SaveSettings(c, settings)
@@ -503,6 +727,26 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		return nil
 	}, CSRF, HTML)
 
+	clearNotifications := handle(func(c *client) error {
+		err := s.ClearNotifications(c)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	dismissNotification := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		ids := append([]string{id}, c.r.Form["grouped_id"]...)
+		err := s.DismissNotification(c, ids)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
 	bookmark := handle(func(c *client) error {
 		id, _ := mux.Vars(c.r)["id"]
 		rid := c.r.FormValue("retweeted_by_id")
@@ -532,9 +776,31 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 	}, CSRF, HTML)
 
 	filter := handle(func(c *client) error {
-		phrase := c.r.FormValue("phrase")
-		wholeWord := c.r.FormValue("whole_word") == "true"
-		err := s.Filter(c, phrase, wholeWord)
+		id, _ := mux.Vars(c.r)["id"]
+		p := &mastodon.FilterParams{
+			Title:        c.r.FormValue("title"),
+			Context:      c.r.Form["context"],
+			FilterAction: c.r.FormValue("filter_action"),
+		}
+		if v := c.r.FormValue("expires_in"); len(v) > 0 {
+			secs, err := strconv.Atoi(v)
+			if err != nil || secs < 0 {
+				return errInvalidArgument
+			}
+			d := time.Duration(secs) * time.Second
+			p.ExpiresIn = &d
+		}
+		n, _ := strconv.Atoi(c.r.FormValue("keyword_count"))
+		for i := 0; i < n && i < maxFilterKeywords; i++ {
+			si := strconv.Itoa(i)
+			p.Keywords = append(p.Keywords, mastodon.FilterKeyword{
+				ID:        c.r.FormValue("keyword_id_" + si),
+				Keyword:   c.r.FormValue("keyword_" + si),
+				WholeWord: c.r.FormValue("whole_word_"+si) == "true",
+				Destroy:   c.r.FormValue("remove_"+si) == "true",
+			})
+		}
+		err := s.Filter(c, id, p)
 		if err != nil {
 			return err
 		}
@@ -552,6 +818,33 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		return nil
 	}, CSRF, HTML)
 
+	addMuteRule := handle(func(c *client) error {
+		r := model.MuteRule{
+			Pattern:    c.r.FormValue("pattern"),
+			Account:    c.r.FormValue("account"),
+			Domain:     c.r.FormValue("domain"),
+			BoostsFrom: c.r.FormValue("boosts_from"),
+			NoMedia:    c.r.FormValue("no_media") == "true",
+			Action:     c.r.FormValue("action"),
+		}
+		err := s.AddMuteRule(c, r)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	removeMuteRule := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		err := s.RemoveMuteRule(c, id)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
 	listsPage := handle(func(c *client) error {
 		return s.ListsPage(c)
 	}, SESSION, HTML)
@@ -618,6 +911,66 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 		return nil
 	}, CSRF, HTML)
 
+	proxyMedia := handle(func(c *client) error {
+		q := c.r.URL.Query()
+		u := q.Get("url")
+		sig := q.Get("sig")
+		return s.ProxyMedia(c, u, sig)
+	}, NOAUTH, MEDIA)
+
+	proxyCardImage := handle(func(c *client) error {
+		q := c.r.URL.Query()
+		u := q.Get("url")
+		sig := q.Get("sig")
+		return s.ProxyCardImage(c, u, sig)
+	}, NOAUTH, MEDIA)
+
+	streamNotifications := handle(func(c *client) error {
+		return s.StreamNotifications(c)
+	}, SESSION, SSE)
+
+	streamTimeline := handle(func(c *client) error {
+		tType, _ := mux.Vars(c.r)["type"]
+		q := c.r.URL.Query()
+		list := q.Get("list")
+		tag := q.Get("tag")
+		return s.StreamTimeline(c, tType, list, tag)
+	}, SESSION, SSE)
+
+	draftsPage := handle(func(c *client) error {
+		return s.DraftsPage(c)
+	}, SESSION, HTML)
+
+	removeDraft := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		err := s.RemoveDraft(c, id)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	readConversation := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		err := s.ReadConversation(c, id)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
+	removeConversation := handle(func(c *client) error {
+		id, _ := mux.Vars(c.r)["id"]
+		err := s.RemoveConversation(c, id)
+		if err != nil {
+			return err
+		}
+		c.redirect(c.r.FormValue("referrer"))
+		return nil
+	}, CSRF, HTML)
+
 	signout := handle(func(c *client) error {
 		c.unsetSession()
 		c.redirect("/")
@@ -670,14 +1023,23 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 	r.HandleFunc("/likedby/{id}", likedByPage).Methods(http.MethodGet)
 	r.HandleFunc("/retweetedby/{id}", retweetedByPage).Methods(http.MethodGet)
 	r.HandleFunc("/notifications", notificationsPage).Methods(http.MethodGet)
+	r.HandleFunc("/conversations", conversationsPage).Methods(http.MethodGet)
+	r.HandleFunc("/conversation/{id}", conversationPage).Methods(http.MethodGet)
 	r.HandleFunc("/user/{id}", userPage).Methods(http.MethodGet)
 	r.HandleFunc("/user/{id}/{type}", userPage).Methods(http.MethodGet)
 	r.HandleFunc("/usersearch/{id}", userSearchPage).Methods(http.MethodGet)
 	r.HandleFunc("/about", aboutPage).Methods(http.MethodGet)
 	r.HandleFunc("/emojis", emojisPage).Methods(http.MethodGet)
 	r.HandleFunc("/search", searchPage).Methods(http.MethodGet)
+	r.HandleFunc("/explore", explorePage).Methods(http.MethodGet)
 	r.HandleFunc("/settings", settingsPage).Methods(http.MethodGet)
 	r.HandleFunc("/filters", filtersPage).Methods(http.MethodGet)
+	r.HandleFunc("/muterules", muteRulesPage).Methods(http.MethodGet)
+	r.HandleFunc("/drafts", draftsPage).Methods(http.MethodGet)
+	r.HandleFunc(mediaproxy.Path, proxyMedia).Methods(http.MethodGet)
+	r.HandleFunc(mediaproxy.CardImagePath, proxyCardImage).Methods(http.MethodGet)
+	r.HandleFunc("/stream/notifications", streamNotifications).Methods(http.MethodGet)
+	r.HandleFunc("/stream/timeline/{type}", streamTimeline).Methods(http.MethodGet)
 	r.HandleFunc("/signin", signin).Methods(http.MethodPost)
 	r.HandleFunc("/oauth_callback", oauthCallback).Methods(http.MethodGet)
 	r.HandleFunc("/post", post).Methods(http.MethodPost)
@@ -696,15 +1058,35 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 	r.HandleFunc("/unblock/{id}", unBlock).Methods(http.MethodPost)
 	r.HandleFunc("/subscribe/{id}", subscribe).Methods(http.MethodPost)
 	r.HandleFunc("/unsubscribe/{id}", unSubscribe).Methods(http.MethodPost)
+	r.HandleFunc("/domainblocks", domainBlocksPage).Methods(http.MethodGet)
+	r.HandleFunc("/blockdomain", blockDomain).Methods(http.MethodPost)
+	r.HandleFunc("/unblockdomain", unBlockDomain).Methods(http.MethodPost)
+	r.HandleFunc("/report/{id}", reportPage).Methods(http.MethodGet)
+	r.HandleFunc("/report/{id}", report).Methods(http.MethodPost)
+	r.HandleFunc("/report/{id}/done", reportedPage).Methods(http.MethodGet)
+	r.HandleFunc("/note/{id}", setNote).Methods(http.MethodPost)
+	r.HandleFunc("/pin/{id}", pin).Methods(http.MethodPost)
+	r.HandleFunc("/unpin/{id}", unPin).Methods(http.MethodPost)
+	r.HandleFunc("/endorse/{id}", endorse).Methods(http.MethodPost)
+	r.HandleFunc("/unendorse/{id}", unEndorse).Methods(http.MethodPost)
+	r.HandleFunc("/tag/{name}/follow", followTag).Methods(http.MethodPost)
+	r.HandleFunc("/tag/{name}/unfollow", unFollowTag).Methods(http.MethodPost)
+	r.HandleFunc("/featuredtag", featureTag).Methods(http.MethodPost)
+	r.HandleFunc("/featuredtag/{id}/remove", unFeatureTag).Methods(http.MethodPost)
 	r.HandleFunc("/settings", settings).Methods(http.MethodPost)
 	r.HandleFunc("/muteconv/{id}", muteConversation).Methods(http.MethodPost)
 	r.HandleFunc("/unmuteconv/{id}", unMuteConversation).Methods(http.MethodPost)
 	r.HandleFunc("/delete/{id}", delete).Methods(http.MethodPost)
 	r.HandleFunc("/notifications/read", readNotifications).Methods(http.MethodPost)
+	r.HandleFunc("/notifications/clear", clearNotifications).Methods(http.MethodPost)
+	r.HandleFunc("/notification/{id}/dismiss", dismissNotification).Methods(http.MethodPost)
 	r.HandleFunc("/bookmark/{id}", bookmark).Methods(http.MethodPost)
 	r.HandleFunc("/unbookmark/{id}", unBookmark).Methods(http.MethodPost)
 	r.HandleFunc("/filter", filter).Methods(http.MethodPost)
+	r.HandleFunc("/filter/{id}", filter).Methods(http.MethodPost)
 	r.HandleFunc("/unfilter/{id}", unFilter).Methods(http.MethodPost)
+	r.HandleFunc("/muterule", addMuteRule).Methods(http.MethodPost)
+	r.HandleFunc("/muterule/{id}/remove", removeMuteRule).Methods(http.MethodPost)
 	r.HandleFunc("/lists", listsPage).Methods(http.MethodGet)
 	r.HandleFunc("/list", addList).Methods(http.MethodPost)
 	r.HandleFunc("/list/{id}", listPage).Methods(http.MethodGet)
@@ -712,6 +1094,9 @@ func NewHandler(s *service, logger *log.Logger, staticDir string) http.Handler {
 	r.HandleFunc("/list/{id}/rename", renameList).Methods(http.MethodPost)
 	r.HandleFunc("/list/{id}/adduser", listAddUser).Methods(http.MethodPost)
 	r.HandleFunc("/list/{id}/removeuser", listRemoveUser).Methods(http.MethodPost)
+	r.HandleFunc("/draft/{id}/remove", removeDraft).Methods(http.MethodPost)
+	r.HandleFunc("/conversation/{id}/read", readConversation).Methods(http.MethodPost)
+	r.HandleFunc("/conversation/{id}/remove", removeConversation).Methods(http.MethodPost)
 	r.HandleFunc("/signout", signout).Methods(http.MethodPost)
 	r.HandleFunc("/fluoride/like/{id}", fLike).Methods(http.MethodPost)
 	r.HandleFunc("/fluoride/unlike/{id}", fUnlike).Methods(http.MethodPost)
@@ -285,33 +285,131 @@ function onPaste(e) {
 	fp.files = dt.files;
 }
 
-document.addEventListener("DOMContentLoaded", function() { 
-	checkCSRFToken();
-	checkAntiDopamineMode();
+function handleStatus(s) {
+	var id = s.dataset.id;
 
-	var statuses = document.querySelectorAll(".status-container");
-	for (var i = 0; i < statuses.length; i++) {
-		var s = statuses[i];
-		var id = s.dataset.id;
+	var likeForm = s.querySelector(".status-like");
+	handleLikeForm(id, likeForm);
 
-		var likeForm = s.querySelector(".status-like");
-		handleLikeForm(id, likeForm);
+	var retweetForm = s.querySelector(".status-retweet");
+	handleRetweetForm(id, retweetForm);
 
-		var retweetForm = s.querySelector(".status-retweet");
-		handleRetweetForm(id, retweetForm);
+	var replyToLink = s.querySelector(".status-reply-to-link");
+	handleReplyToLink(replyToLink);
 
-		var replyToLink = s.querySelector(".status-reply-to-link");
-		handleReplyToLink(replyToLink);
+	var replyLinks = s.querySelectorAll(".status-reply-link");
+	for (var j = 0; j < replyLinks.length; j++) {
+		handleReplyLink(replyLinks[j]);
+	}
+
+	var links = s.querySelectorAll(".status-content a");
+	for (var j = 0; j < links.length; j++) {
+		handleStatusLink(links[j]);
+	}
+}
 
-		var replyLinks = s.querySelectorAll(".status-reply-link");
-		for (var j = 0; j < replyLinks.length; j++) {
-			handleReplyLink(replyLinks[j]);
+function handleTimelineStream(bar) {
+	if (typeof EventSource === "undefined")
+		return;
+	var list = document.querySelector(".timeline-statuses");
+	var link = bar.querySelector(".timeline-new-link");
+	var pending = [];
+
+	var update = function() {
+		bar.hidden = pending.length === 0;
+		link.textContent = pending.length + " new " +
+			(pending.length === 1 ? "post" : "posts");
+	};
+	var has = function(id) {
+		if (document.getElementById("status-" + id))
+			return true;
+		for (var i = 0; i < pending.length; i++) {
+			if (pending[i].id === "status-" + id)
+				return true;
 		}
+		return false;
+	};
 
-		var links = s.querySelectorAll(".status-content a");
-		for (var j = 0; j < links.length; j++) {
-			handleStatusLink(links[j]);
+	var es = new EventSource(bar.dataset.stream);
+	es.addEventListener("update", function(e) {
+		var div = document.createElement("div");
+		div.innerHTML = e.data;
+		var el = div.firstElementChild;
+		if (!el || has(el.id.replace("status-", "")))
+			return;
+		pending.push(el);
+		update();
+	});
+	es.addEventListener("delete", function(e) {
+		for (var i = 0; i < pending.length; i++) {
+			if (pending[i].id === "status-" + e.data) {
+				pending.splice(i, 1);
+				update();
+				return;
+			}
+		}
+		var el = document.getElementById("status-" + e.data);
+		if (el)
+			el.parentNode.removeChild(el);
+	});
+
+	link.onclick = function() {
+		for (var i = 0; i < pending.length; i++) {
+			var el = pending[i];
+			list.insertBefore(el, list.firstChild);
+			var statuses = el.querySelectorAll(".status-container");
+			for (var j = 0; j < statuses.length; j++) {
+				handleStatus(statuses[j]);
+			}
+			var links = el.querySelectorAll(".status-media-container .img-link");
+			for (var j = 0; j < links.length; j++) {
+				handleImgPreview(links[j]);
+			}
 		}
+		pending = [];
+		update();
+		window.scrollTo(0, 0);
+	};
+}
+
+function handleNotificationStream(tag) {
+	var interval = parseInt(tag.dataset.interval, 10);
+	var reload = function() {
+		setTimeout(function() { location.reload(); }, interval * 1000);
+	};
+	if (typeof EventSource === "undefined") {
+		reload();
+		return;
+	}
+
+	var countEl = document.querySelector(".notification-count");
+	var count = 0;
+	if (countEl)
+		count = parseInt(countEl.dataset.count, 10) || 0;
+	var title = document.title.replace(/^\s*\(\d+\)\s*/, "");
+
+	var es = new EventSource(tag.getAttribute("content"));
+	es.addEventListener("notification", function() {
+		count++;
+		document.title = "(" + count + ") " + title;
+		if (countEl && !antiDopamineMode)
+			countEl.textContent = "(" + count + ")";
+	});
+	es.onerror = function() {
+		// The browser reconnects by itself unless the server refused
+		// the stream, fall back to reloading the page in that case.
+		if (es.readyState === EventSource.CLOSED)
+			reload();
+	};
+}
+
+document.addEventListener("DOMContentLoaded", function() { 
+	checkCSRFToken();
+	checkAntiDopamineMode();
+
+	var statuses = document.querySelectorAll(".status-container");
+	for (var i = 0; i < statuses.length; i++) {
+		handleStatus(statuses[i]);
 	}
 
 	var links = document.querySelectorAll(".user-profile-decription a, .user-fields a");
@@ -327,6 +425,14 @@ document.addEventListener("DOMContentLoaded", function() {
 	var pf = document.querySelector(".post-form")
 	if (pf)
 		pf.addEventListener("paste", onPaste);
+
+	var ns = document.querySelector("meta[name='notification_stream']");
+	if (ns)
+		handleNotificationStream(ns);
+
+	var tn = document.querySelector(".timeline-new");
+	if (tn)
+		handleTimelineStream(tn);
 });
 
 // @license-end
@@ -23,3 +23,15 @@ func NewSessionID() (string, error) {
 func NewCSRFToken() (string, error) {
 	return NewRandID(24)
 }
+
+func NewIdempotencyKey() (string, error) {
+	return NewRandID(24)
+}
+
+func NewDraftID() (string, error) {
+	return NewRandID(12)
+}
+
+func NewMuteRuleID() (string, error) {
+	return NewRandID(12)
+}